@@ -1,9 +1,11 @@
 package container
 
 import (
+	"apier/internal/global/consts"
 	"apier/internal/global/custom_errors"
 	"apier/internal/global/variable"
 	"log"
+	"reflect"
 	"strings"
 	"sync"
 )
@@ -66,3 +68,41 @@ func (c *containers) FuzzyDelete(keyPre string) {
 		return true
 	})
 }
+
+// Keys 按照键的前缀获取容器中已注册的键名
+func (c *containers) Keys(keyPre string) []string {
+	keys := make([]string, 0)
+	syncMap.Range(func(key, value interface{}) bool {
+		if keyName, ok := key.(string); ok {
+			if strings.HasPrefix(keyName, keyPre) {
+				keys = append(keys, keyName)
+			}
+		}
+		return true
+	})
+	return keys
+}
+
+// Count 按照键的前缀统计容器中已注册的键数量
+func (c *containers) Count(keyPre string) int {
+	return len(c.Keys(keyPre))
+}
+
+// RegisterTyped 以类型为键将已解析的配置对象注册到容器，模块之间无需约定字符串键名即可共享
+func RegisterTyped[T any](instance T) bool {
+	return CreateContainersFactory().Set(typedKey[T](), instance)
+}
+
+// ResolveTyped 按类型从容器获取配置对象，未注册时 ok 为 false
+func ResolveTyped[T any]() (instance T, ok bool) {
+	if value, exists := CreateContainersFactory().KeyIsExists(typedKey[T]()); exists {
+		instance, ok = value.(T)
+	}
+	return
+}
+
+// typedKey 类型对应的容器键名，包含包路径，避免不同包的同名类型冲突
+func typedKey[T any]() string {
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	return consts.TypedConfigPrefix + typ.PkgPath() + "." + typ.String()
+}
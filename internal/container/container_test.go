@@ -0,0 +1,29 @@
+package container
+
+import (
+	"testing"
+)
+
+type testDbConfig struct {
+	Host string
+	Port int
+}
+
+func TestRegisterAndResolveTyped(t *testing.T) {
+	if _, ok := ResolveTyped[testDbConfig](); ok {
+		t.Fatal("unregistered type should not resolve")
+	}
+
+	if !RegisterTyped(testDbConfig{Host: "127.0.0.1", Port: 3306}) {
+		t.Fatal("RegisterTyped failed")
+	}
+	t.Cleanup(func() { CreateContainersFactory().Delete(typedKey[testDbConfig]()) })
+
+	dbConfig, ok := ResolveTyped[testDbConfig]()
+	if !ok || dbConfig.Host != "127.0.0.1" || dbConfig.Port != 3306 {
+		t.Fatalf("ResolveTyped = %+v, %v", dbConfig, ok)
+	}
+	if _, ok := ResolveTyped[*testDbConfig](); ok {
+		t.Fatal("pointer type must be registered separately")
+	}
+}
@@ -11,6 +11,9 @@ const (
 	ValidatorParamsCheckFailCode int    = -400300
 	ValidatorParamsCheckFailMsg  string = "参数校验失败"
 
+	// 按类型注册到容器的配置对象前缀
+	TypedConfigPrefix string = "Typed_Config_"
+
 	// 服务器代码发生错误
 	ServerOccurredErrorCode int    = -500100
 	ServerOccurredErrorMsg  string = "服务器内部发生代码执行错误, "
@@ -8,6 +8,46 @@ const (
 	ErrorsConfigGormNotExists       string = "gorm.yml 配置文件不存在"
 	ErrorsStorageLogsNotExists      string = "storage/logs 目录不存在"
 	ErrorsConfigInitFail            string = "初始化配置文件发生错误"
+	ErrorsConfigReloadFail          string = "重新载入配置文件发生错误"
+	ErrorsConfigStdinNoReload       string = "配置来自标准输入，不支持文件监听与重新载入"
+	ErrorsConfigReloadCanary        string = "新配置未通过重新载入前的检查，已保留旧配置："
+	ErrorsConfigReloadTypes         string = "新配置未通过强制类型校验，已保留旧配置："
+	ErrorsConfigNotLoaded           string = "配置文件尚未载入，无法读取配置项："
+	ErrorsConfigKeyNotExists        string = "配置项不存在，相关键："
+	ErrorsConfigVersionNotExists    string = "配置文件缺少版本号 config_version"
+	ErrorsConfigVersionMismatch     string = "配置文件版本不匹配，期望版本：%d，实际版本：%d"
+	ErrorsConfigTypeNotSupport      string = "不支持的配置项类型："
+	ErrorsConfigValueTypeMismatch   string = "配置项 %s 的值无法转换为类型 %s：%s"
+	ErrorsConfigTypeInconsistent    string = "配置项 %s 在不同文件中的类型不一致：%s"
+	ErrorsConfigKeyFrozen           string = "配置项已被冻结，不允许修改，相关键："
+	ErrorsConfigCacheTypeMismatch   string = "配置项 %s 的缓存值类型为 %T，与声明的类型 %s 不一致"
+	ErrorsConfigCacheStale          string = "持久化的缓存与当前配置不一致，已忽略，相关文件："
+	ErrorsConfigNoSecretResolver    string = "未设置密钥解析器，无法解析密钥引用："
+	ErrorsConfigSecretRefInvalid    string = "密钥引用格式错误，正确格式为 keyring:service/account，相关值："
+	ErrorsConfigSecretResolveFail   string = "解析密钥引用失败，相关键："
+	ErrorsConfigNoDecryptor         string = "未设置解密器，无法解密配置值，相关值："
+	ErrorsConfigNoEncryptor         string = "未设置加密器，无法写出包含明文敏感项的配置，相关键："
+	ErrorsConfigExtendsCycle        string = "配置段的 _extends 存在循环继承："
+	ErrorsConfigRequiredIf          string = "配置项 %s 在 %s 为 %v 时必须设置"
+	ErrorsConfigBindFail            string = "配置段 %s 绑定或校验失败：%w"
+	ErrorsConfigProtoUnmarshal      string = "配置段 %s 转换为 protobuf 消息失败：%w"
+	ErrorsConfigLoadIntoTarget      string = "LoadInto 的参数必须是 *atomic.Pointer[T]，且 T 为结构体，实际类型："
+	ErrorsConfigGlobNoMatch         string = "配置项中的路径模式没有匹配任何文件："
+	ErrorsConfigNotStringMap        string = "配置项不是字典格式，相关键："
+	ErrorsConfigValueNotString      string = "配置项的值不是字符串，相关键："
+	ErrorsConfigMapValueMismatch    string = "字典配置项 %s 中以下子键的值不符合格式 %s：%s"
+	ErrorsConfigEmptySlice          string = "配置项不能为空列表，相关键："
+	ErrorsConfigDurationNoUnit      string = "时间配置项必须带单位（例如 30s、5m），相关键："
+	ErrorsConfigSliceElement        string = "配置项 %s 的第 %d 个元素转换失败：%w"
+	ErrorsConfigValueMigrated       string = "配置项使用了已废弃的取值，已自动迁移为新值，相关键："
+	ErrorsConfigTransformUnknown    string = "transforms 配置段中存在未知的转换："
+	ErrorsConfigChecksumFail        string = "计算配置摘要失败"
+	ErrorsConfigRefCycle            string = "字典配置项的 ${} 引用存在循环："
+	ErrorsConfigRefNotExists        string = "字典配置项引用的子键不存在："
+	ErrorsConfigUnknownSection      string = "配置文件中存在未知的顶级配置段："
+	ErrorsConfigFileNotMerged       string = "该文件没有通过 MergeConfig 合并过，无法单独重新载入："
+	ErrorsConfigLogLevelInvalid     string = "配置项中的日志级别无效，相关键："
+	ErrorsDotEnvLineInvalid         string = ".env 文件格式错误，%s 第 %d 行：%s"
 	ErrorsSoftLinkCreateFail        string = "自动创建软连接失败,请以管理员身份运行客户端(开发环境为goland等，生产环境检查命令执行者权限), 最后一个可能：如果您是360用户，请退出360相关软件，才能保证go语言创建软连接函数： os.Symlink() 正常运行"
 	ErrorsSoftLinkDeleteFail        string = "删除软软连接失败"
 	ErrorsFuncEventAlreadyExists    string = "注册函数类事件失败，键名已经被注册"
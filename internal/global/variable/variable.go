@@ -17,6 +17,8 @@ var (
 
 	// 全局日志指针
 	ZapLog *zap.Logger
+	// 全局日志级别，ZapLog 基于该级别创建，可通过配置的 EnableDynamicLogLevel 随配置文件热更新
+	ZapLogLevel = zap.NewAtomicLevelAt(zap.InfoLevel)
 
 	// 全局配置文件
 	ConfigYaml     yaml_config_interface.YamlConfigInterface // 全局配置文件指针
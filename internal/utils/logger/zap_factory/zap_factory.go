@@ -16,7 +16,10 @@ func CreateZapFactory(entry func(zapcore.Entry) error) *zap.Logger {
 
 	// 判断程序当前所处的模式，调试模式直接返回一个便捷的zap日志管理器地址，所有的日志打印到控制台即可
 	if appDebug == true {
-		if logger, err := zap.NewDevelopment(zap.Hooks(entry)); err == nil {
+		variable.ZapLogLevel.SetLevel(zap.DebugLevel)
+		developmentConfig := zap.NewDevelopmentConfig()
+		developmentConfig.Level = variable.ZapLogLevel
+		if logger, err := developmentConfig.Build(zap.Hooks(entry)); err == nil {
 			return logger
 		} else {
 			log.Fatal("创建zap日志包失败，详情：" + err.Error())
@@ -66,6 +69,7 @@ func CreateZapFactory(entry func(zapcore.Entry) error) *zap.Logger {
 	// 开始初始化zap日志核心参数，
 
 	// zapCore 编码器 写入器 参数级别（debug级别支持后续调用的所有函数写日志，如果是 fatal 高级别，则级别>=fatal 才可以写日志）
-	zapCore := zapcore.NewCore(encoder, writer, zap.InfoLevel)
+	// 级别使用全局的 variable.ZapLogLevel，默认 info，运行时可以通过配置调整
+	zapCore := zapcore.NewCore(encoder, writer, variable.ZapLogLevel)
 	return zap.New(zapCore, zap.AddCaller(), zap.Hooks(entry), zap.AddStacktrace(zap.WarnLevel))
 }
@@ -5,211 +5,1395 @@ import (
 	"apier/internal/global/custom_errors"
 	"apier/internal/global/variable"
 	"apier/internal/utils/yaml_config/yaml_config_interface"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cast"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"go.uber.org/zap"
+	"io/fs"
 	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 /*
-  由于 vipver 包本身对于文件的变化事件有一个bug，相关事件会被回调两次。
-  为解决这个问题，设置一个内部全局变量，记录配置文件变化时的时间点。
-  如果两次回调事件事件差小于1秒，我们认为是第二次回调事件，而不是人工修改配置文件，以此来避免 viper 包的这个bug
+  保存一次配置文件通常会连续触发多次写事件（截断、写入、编辑器的临时文件重命名等），
+  如果每个事件都立即重新载入，可能读到只写了一半的文件。
+  因此文件变化后等待一小段时间，期间没有新的事件才真正重新载入，多次事件合并为一次 Reload
 */
 
-var lastChangeTime time.Time
+// 文件变化后等待重新载入的时间
+const configChangeDebounce = 100 * time.Millisecond
+
+// 配置文件结构版本号所在的键
+const configVersionKey = "config_version"
+
 var containerFactory = container.CreateContainersFactory()
 
-func init() {
-	lastChangeTime = time.Now()
+// 每个配置实例在容器中使用独立的缓存前缀，形如 Config_1_，避免不同配置文件的同名键相互覆盖
+var cachePrefixSeq int64
+
+func newCachePrefix() string {
+	return variable.ConfigKeyPrefix + strconv.FormatInt(atomic.AddInt64(&cachePrefixSeq, 1), 10) + "_"
 }
 
 func CreateYamlFactory(fileName ...string) yaml_config_interface.YamlConfigInterface {
 
-	configInstance := viper.New()
-	configInstance.AddConfigPath(variable.BasePath + "/configs") // 配置文件所在目录
-
 	// 需要读取的文件名,默认为：config
-	if len(fileName) == 0 {
-		configInstance.SetConfigName("config")
-	} else {
-		configInstance.SetConfigName(fileName[0])
+	name := "config"
+	if len(fileName) > 0 {
+		name = fileName[0]
 	}
 
-	//设置配置文件类型(后缀)为 yml
-	configInstance.SetConfigType("yml")
-
-	if err := configInstance.ReadInConfig(); err != nil {
+	ymlConfig := newYamlConfig(name)
+	if err := ymlConfig.viper.ReadInConfig(); err != nil {
 		log.Fatal(custom_errors.ErrorsConfigInitFail + err.Error())
 	}
+	ymlConfig.markLoaded()
+	return ymlConfig
+}
 
-	return &yamlConfig{
-		viper: configInstance,
-		mu:    new(sync.Mutex),
+// CreateYamlFactoryStrict 与 CreateYamlFactory 相同，但配置文件中出现 knownSections 以外的顶级配置段时返回错误，用于发现重构后残留或放错位置的配置
+// 配置段名称不区分大小写，config_version 与 transforms 总是允许的；读取失败同样返回错误而不是终止程序
+func CreateYamlFactoryStrict(knownSections []string, fileName ...string) (yaml_config_interface.YamlConfigInterface, error) {
+	name := "config"
+	if len(fileName) > 0 {
+		name = fileName[0]
 	}
 
+	ymlConfig := newYamlConfig(name)
+	if err := ymlConfig.viper.ReadInConfig(); err != nil {
+		return nil, errors.New(custom_errors.ErrorsConfigInitFail + err.Error())
+	}
+	known := map[string]struct{}{configVersionKey: {}, transformsKey: {}}
+	for _, section := range knownSections {
+		known[strings.ToLower(section)] = struct{}{}
+	}
+	unknown := make([]string, 0)
+	for section := range ymlConfig.viper.AllSettings() {
+		if _, exists := known[section]; !exists {
+			unknown = append(unknown, section)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, errors.New(custom_errors.ErrorsConfigUnknownSection + strings.Join(unknown, ", "))
+	}
+	ymlConfig.markLoaded()
+	return ymlConfig, nil
 }
 
+// newYamlConfig 创建一个尚未读取配置文件的实例
+func newYamlConfig(fileName string) *yamlConfig {
+	configInstance := viper.New()
+	configInstance.AddConfigPath(variable.BasePath + "/configs") // 配置文件所在目录
+	configInstance.SetConfigName(fileName)
+
+	//设置配置文件类型(后缀)为 yml
+	configInstance.SetConfigType("yml")
+
+	return &yamlConfig{
+		viper:       configInstance,
+		mu:          new(sync.Mutex),
+		cachePrefix: newCachePrefix(),
+		cacheGen:    new(atomic.Uint64),
+		watch:       new(watchState),
+		subscribers: newKeySubscribers(),
+		frozen:      newFrozenKeys(),
+		types:       newDeclaredTypes(),
+		secrets:     newSecretStore(nil),
+		seal:        new(sealState),
+		opts:        new(instanceOptions),
+		rules:       new(requireRules),
+		history:     newValueHistory(),
+		migrations:  newValueMigrations(),
+		providers:   newMapProviders(),
+		aliases:     newSectionAliases(),
+		roots:       new(rootBindings),
+		latencies:   newReadLatencies(),
+		patterns:    newKeyPatternCache(),
+		logLevel:    new(logLevelBinding),
+		canary:      new(reloadCanary),
+		changes:     newChangeCallbacks(),
+		merges:      newMergeTracking(),
+		loaded:      new(atomic.Bool),
+		loadedAt:    new(atomic.Int64),
+	}
+}
+
+// yamlConfig 配置实例，全部导出方法都可以被多个 goroutine 并发调用
+//
+// 并发约定：
+//   - viper 本身不是并发安全的，对 viper 的读写都必须持有 mu；getter 只在读取 viper、写入缓存的瞬间持有 mu，不会在持有 mu 时调用其它 getter
+//   - 缓存读取不加锁，只做一次容器查找，缓存恰好被清空时视为未命中，重新读取 viper
+//   - 每次清空缓存 cacheGen 加一，getter 在读取 viper 之前记下 cacheGen，写入缓存时若已发生变化则放弃写入，
+//     保证与 Reload、Set 并发时不会把重新载入之前的旧值写进新的缓存
+//   - 锁的顺序固定为先 mu 再各个子结构自身的锁（seal、secrets、frozen、rules、history、subscribers），子结构持有自身锁时不会再获取 mu
 type yamlConfig struct {
-	viper *viper.Viper
-	mu    *sync.Mutex
+	viper       *viper.Viper
+	mu          sync.Locker // 通常为 *sync.Mutex，所有 viper 访问都经过它
+	cachePrefix string
+	cacheGen    *atomic.Uint64 // 缓存代数，CloneShared 出的实例与原实例共用
+	watch       *watchState    // 文件监听的状态，由 mu 保护
+	subscribers *keySubscribers
+	frozen      *frozenKeys
+	types       *declaredTypes
+	secrets     *secretStore
+	seal        *sealState
+	opts        *instanceOptions
+	rules       *requireRules
+	history     *valueHistory
+	migrations  *valueMigrations
+	providers   *mapProviders
+	aliases     *sectionAliases
+	roots       *rootBindings
+	latencies   *readLatencies
+	patterns    *keyPatternCache
+	logLevel    *logLevelBinding
+	canary      *reloadCanary
+	changes     *changeCallbacks
+	mergedFiles []string
+	merges      *mergeTracking // 主配置与各个合并文件分别提供的键，由 mu 保护
+	embedded    []byte         // CreateYamlFactoryWithDefaults 内嵌的默认配置
+	stdin       bool           // 配置来自标准输入，不支持监听与重新载入
+	loaded      *atomic.Bool   // 配置文件是否已成功读取，零值实例为 nil，视为未载入
+	loadedAt    *atomic.Int64  // 最近一次成功读取的时间（UnixNano）
 }
 
-// ConfigFileChangeListen 监听文件变化
+// ConfigFileChangeListen 监听文件变化，文件保存后重新载入配置，与 ListenSignals 一样最终通过 Reload 生效
+// 监听的是配置文件所在的目录，编辑器以“写临时文件再重命名”的方式保存时同样能够收到事件
 func (y *yamlConfig) ConfigFileChangeListen() {
-	y.viper.OnConfigChange(func(changeEvent fsnotify.Event) {
-		if time.Now().Sub(lastChangeTime).Seconds() >= 1 {
-			if changeEvent.Op.String() == "WRITE" {
-				y.clearCache()
-				lastChangeTime = time.Now()
+	if y.stdin {
+		logWarn(custom_errors.ErrorsConfigStdinNoReload)
+		return
+	}
+	y.mu.Lock()
+	configFile := filepath.Clean(y.viper.ConfigFileUsed())
+	y.mu.Unlock()
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		logError(custom_errors.ErrorsConfigReloadFail, zap.Error(err))
+		return
+	}
+	if err := watcher.Add(filepath.Dir(configFile)); err != nil {
+		_ = watcher.Close()
+		logError(custom_errors.ErrorsConfigReloadFail, zap.Error(err))
+		return
+	}
+	go func() {
+		defer watcher.Close()
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) == configFile && event.Op&(fsnotify.Write|fsnotify.Create) != 0 {
+					y.scheduleReload()
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				logError(custom_errors.ErrorsConfigReloadFail, zap.Error(err))
+			}
+		}
+	}()
+}
+
+// Reload 重新读取配置文件并清空已缓存的配置项，文件监听与信号监听共用该入口；设置了 SetReloadCanary、EnforceTypes 时检查通过才会生效
+func (y *yamlConfig) Reload() error {
+	if y.viper == nil {
+		return errors.New(custom_errors.ErrorsConfigNotLoaded)
+	}
+	y.mu.Lock()
+	capture := y.beginChange()
+	if err := y.reload(); err != nil {
+		y.mu.Unlock()
+		return err
+	}
+	event := y.endChange(capture)
+	y.mu.Unlock()
+	y.dispatchChange(event)
+	y.dispatchSuperseded()
+	return nil
+}
+
+// reload Reload 的实现，调用方需持有 y.mu
+func (y *yamlConfig) reload() error {
+	if canary := y.canary.get(); canary != nil || y.hasEnforcedTypes() {
+		candidate, err := y.loadCandidate()
+		if err != nil {
+			return err
+		}
+		if err := y.checkEnforcedTypesIn(candidate.viper); err != nil {
+			return fmt.Errorf(custom_errors.ErrorsConfigReloadTypes+"%w", err)
+		}
+		if canary != nil {
+			// 检查函数通过临时实例读取的值缓存在全局容器中，无论是否通过都要清除
+			err = canary(candidate)
+			candidate.clearCache()
+			if err != nil {
+				return fmt.Errorf(custom_errors.ErrorsConfigReloadCanary+"%w", err)
 			}
 		}
-	})
-	y.viper.WatchConfig()
+		*y.viper = *candidate.viper
+		*y.merges = *candidate.merges
+	} else {
+		if err := y.readConfig(); err != nil {
+			return err
+		}
+		if err := y.applyMergedFiles(); err != nil {
+			return err
+		}
+	}
+	y.markLoaded()
+	y.afterReload()
+	return nil
+}
+
+// afterReload 配置文件重新读取后的统一处理：清空缓存、检查冻结键、重新封存、校验规则、通知订阅者，调用方需持有 y.mu
+func (y *yamlConfig) afterReload() {
+	y.clearCache()
+	y.afterConfigChange()
+}
+
+// afterConfigChange afterReload 中清空缓存以外的部分，ReloadMergedFile 只失效相关的缓存后调用，调用方需持有 y.mu
+func (y *yamlConfig) afterConfigChange() {
+	y.secrets.clear()
+	y.checkFrozenKeys()
+	y.reseal()
+	y.checkRulesAfterReload()
+	y.recordHistory()
+	y.rebindRoots()
+	y.reapplyLogLevel()
+	y.notifyKeyChanges()
+}
+
+// Set 在运行时修改某个键的值，被 FreezeKeys 冻结的键不允许修改
+func (y *yamlConfig) Set(keyName string, value interface{}) error {
+	if y.frozen.isFrozen(keyName) {
+		return errors.New(custom_errors.ErrorsConfigKeyFrozen + keyName)
+	}
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	y.viper.Set(keyName, value)
+	y.clearCache()
+	y.reseal()
+	return nil
+}
+
+// BindPFlags 绑定命令行参数，参数优先级遵循 viper 的规则（显式设置的命令行参数高于配置文件）
+// 绑定后清空缓存，保证后续读取的是合并后的最终值
+func (y *yamlConfig) BindPFlags(flagSet *pflag.FlagSet) error {
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	if err := y.viper.BindPFlags(flagSet); err != nil {
+		return err
+	}
+	y.clearCache()
+	return nil
+}
+
+// AutomaticEnv 开启环境变量覆盖，键名中的 . 替换为 _ 后转大写，例如 envPrefix 为 APIER 时 Db.Host 对应 APIER_DB_HOST
+func (y *yamlConfig) AutomaticEnv(envPrefix string) {
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	y.viper.SetEnvPrefix(envPrefix)
+	y.viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	y.viper.AutomaticEnv()
+	y.clearCache()
+	y.reseal()
+}
+
+// Warmup 预先读取并缓存指定的键，避免首次请求时才访问 viper，不存在的键仅记录日志
+func (y *yamlConfig) Warmup(keys ...string) {
+	for _, keyName := range keys {
+		if !y.ready(keyName) {
+			return
+		}
+		if !y.viperIsSet(keyName) {
+			logWarn(custom_errors.ErrorsConfigKeyNotExists + keyName)
+			continue
+		}
+		y.Get(keyName)
+	}
+}
+
+// CacheSnapshot 导出当前实例已缓存的配置项（键名不含缓存前缀，值为深拷贝），可配合 RestoreCache 在测试中固定缓存状态或在切换时快速恢复
+func (y *yamlConfig) CacheSnapshot() map[string]interface{} {
+	snapshot := make(map[string]interface{})
+	for _, key := range containerFactory.Keys(y.cachePrefix) {
+		if value, exists := containerFactory.KeyIsExists(key); exists {
+			snapshot[strings.TrimPrefix(key, y.cachePrefix)] = deepCopyValue(value)
+		}
+	}
+	return snapshot
+}
+
+// RestoreCache 清空当前实例的缓存并写入 CacheSnapshot 导出的内容，之后的读取直接返回快照中的值
+func (y *yamlConfig) RestoreCache(snapshot map[string]interface{}) {
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	y.clearCache()
+	for keyName, value := range snapshot {
+		containerFactory.Set(y.cachePrefix+keyName, deepCopyValue(value))
+	}
+}
+
+// CheckVersion 校验配置文件的 config_version 与程序期望的版本一致，避免升级后使用了过期的配置文件
+func (y *yamlConfig) CheckVersion(expected int) error {
+	if !y.viperIsSet(configVersionKey) {
+		return errors.New(custom_errors.ErrorsConfigVersionNotExists)
+	}
+	if actual := cast.ToInt(y.viperGet(configVersionKey)); actual != expected {
+		return fmt.Errorf(custom_errors.ErrorsConfigVersionMismatch, expected, actual)
+	}
+	return nil
+}
+
+// markLoaded 标记配置已成功读取，并记录读取时间
+func (y *yamlConfig) markLoaded() {
+	y.loaded.Store(true)
+	y.loadedAt.Store(time.Now().UnixNano())
+}
+
+// ConfigModTime 返回主配置文件的修改时间，可用于配置下发接口的 Last-Modified 等缓存头
+// 没有对应文件的配置（标准输入、外部文件不存在时的内嵌默认配置）返回最近一次成功载入的时间
+func (y *yamlConfig) ConfigModTime() (time.Time, error) {
+	if !y.ready("") {
+		return time.Time{}, errors.New(custom_errors.ErrorsConfigNotLoaded)
+	}
+	y.mu.Lock()
+	configFile := y.viper.ConfigFileUsed()
+	y.mu.Unlock()
+	if !y.stdin && configFile != "" {
+		info, err := os.Stat(configFile)
+		if err == nil {
+			return info.ModTime(), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) || y.embedded == nil {
+			return time.Time{}, err
+		}
+	}
+	return time.Unix(0, y.loadedAt.Load()), nil
+}
+
+// ready 判断配置是否已经载入，未载入（例如零值实例）时记录告警，调用方应直接返回零值，避免访问空的 viper 指针
+func (y *yamlConfig) ready(keyName string) bool {
+	if y.viper != nil && y.loaded != nil && y.loaded.Load() {
+		return true
+	}
+	logWarn(custom_errors.ErrorsConfigNotLoaded + keyName)
+	return false
+}
+
+// viperGet 持有 y.mu 从 viper 读取原始值，viper 的 GetString 等方法同样是对 Get 的结果做类型转换，getter 统一经由这里读取
+// 键名位于 RegisterSectionAlias 登记的旧配置段下时，读取的是新配置段下的值
+func (y *yamlConfig) viperGet(keyName string) interface{} {
+	start := time.Now()
+	defer func() { y.latencies.record(keyName, time.Since(start)) }()
+	keyName = y.resolveAlias(keyName)
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	return y.viper.Get(keyName)
+}
+
+// rawGet 读取原始值，已封存时读取快照，否则读取 viper，不经过缓存；同一键名的缓存可能由 GetDuration 等 getter 写入了转换后的类型
+func (y *yamlConfig) rawGet(keyName string) interface{} {
+	if value, sealed := y.sealedGet(keyName); sealed {
+		return value
+	}
+	return y.viperGet(keyName)
+}
+
+// viperIsSet 持有 y.mu 判断键是否存在
+func (y *yamlConfig) viperIsSet(keyName string) bool {
+	keyName = y.resolveAlias(keyName)
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	return y.viper.IsSet(keyName)
 }
 
 // keyIsCache 判断相关键是否已经缓存
 func (y *yamlConfig) keyIsCache(keyName string) bool {
-	if _, exists := containerFactory.KeyIsExists(variable.ConfigKeyPrefix + keyName); exists {
+	if _, exists := containerFactory.KeyIsExists(y.cachePrefix + keyName); exists {
 		return true
 	} else {
 		return false
 	}
 }
 
-// 对键值进行缓存
-func (y *yamlConfig) cache(keyName string, value interface{}) bool {
+// generation 当前缓存代数，getter 在读取 viper 之前获取，写入缓存时传给 cache
+func (y *yamlConfig) generation() uint64 {
+	return y.cacheGen.Load()
+}
+
+// 对键值进行缓存，gen 为读取值之前的缓存代数，期间缓存被清空过（配置已重新载入）时放弃写入
+// 默认不缓存 viper 中不存在的键（见 SetNilCachePolicy），带 # 的组合缓存键不做该检查；封存后不再写入缓存，也就不再为此访问 viper
+func (y *yamlConfig) cache(gen uint64, keyName string, value interface{}) bool {
+	if y.seal.isSealed() {
+		return false
+	}
 	// 避免瞬间缓存键、值时，程序提示键名已经被注册的日志输出
 	y.mu.Lock()
 	defer y.mu.Unlock()
-	if _, exists := containerFactory.KeyIsExists(variable.ConfigKeyPrefix + keyName); exists {
+	if y.cacheGen.Load() != gen {
+		return false
+	}
+	if yaml_config_interface.NilCachePolicy(y.opts.nilCachePolicy.Load()) == yaml_config_interface.NilCacheSkip &&
+		!strings.Contains(keyName, "#") && !y.viper.IsSet(y.resolveAlias(keyName)) {
+		return false
+	}
+	if _, exists := containerFactory.KeyIsExists(y.cachePrefix + keyName); exists {
 		return true
 	}
-	return containerFactory.Set(variable.ConfigKeyPrefix+keyName, value)
+	return containerFactory.Set(y.cachePrefix+keyName, value)
 }
 
-// 通过键获取缓存的值
+// 通过键获取缓存的值，未缓存时返回 nil
 func (y *yamlConfig) getValueFromCache(keyName string) interface{} {
-	return containerFactory.Get(variable.ConfigKeyPrefix + keyName)
+	value, _ := containerFactory.KeyIsExists(y.cachePrefix + keyName)
+	return value
 }
 
 // 清空已经缓存的配置项信息
 func (y *yamlConfig) clearCache() {
-	containerFactory.FuzzyDelete(variable.ConfigKeyPrefix)
+	y.cacheGen.Add(1)
+	containerFactory.FuzzyDelete(y.cachePrefix)
+}
+
+// 记录普通日志，规则同 logWarn
+func logInfo(msg string, fields ...zap.Field) {
+	if variable.ZapLog == nil {
+		log.Println(msg, fields)
+		return
+	}
+	variable.ZapLog.Info(msg, fields...)
+}
+
+// 记录告警日志，程序启动阶段 zaplog 可能尚未初始化，此时使用系统 log 输出
+func logWarn(msg string, fields ...zap.Field) {
+	if variable.ZapLog == nil {
+		log.Println(msg, fields)
+		return
+	}
+	variable.ZapLog.Warn(msg, fields...)
+}
+
+// 记录错误日志，规则同 logWarn
+func logError(msg string, fields ...zap.Field) {
+	if variable.ZapLog == nil {
+		log.Println(msg, fields)
+		return
+	}
+	variable.ZapLog.Error(msg, fields...)
 }
 
-// Clone 允许 clone 一个相同功能的结构体
+// Clone 允许 clone 一个相同功能的结构体，clone 出的实例使用独立的缓存空间
+// 文件读取失败时仅记录日志，返回的实例处于未载入状态，需要感知失败的调用方请使用 CloneE
 func (y *yamlConfig) Clone(fileName string) yaml_config_interface.YamlConfigInterface {
+	ymlC, err := y.clone(fileName, newCachePrefix(), new(atomic.Uint64))
+	if err != nil {
+		logError(custom_errors.ErrorsConfigInitFail, zap.Error(err))
+	}
+	return ymlC
+}
+
+// CloneE 与 Clone 相同，但文件读取失败时返回错误
+func (y *yamlConfig) CloneE(fileName string) (yaml_config_interface.YamlConfigInterface, error) {
+	ymlC, err := y.clone(fileName, newCachePrefix(), new(atomic.Uint64))
+	if err != nil {
+		return nil, err
+	}
+	return ymlC, nil
+}
+
+// CloneShared 与 Clone 相同，但与原实例共用同一个缓存空间，任意一方重新载入都会同时清空双方的缓存
+// 代价是两个文件中的同名键会共用一个缓存值（先读取的一方生效），仅适用于键不重叠、需要同步失效的紧耦合配置
+func (y *yamlConfig) CloneShared(fileName string) yaml_config_interface.YamlConfigInterface {
+	ymlC, err := y.clone(fileName, y.cachePrefix, y.cacheGen)
+	if err != nil {
+		logError(custom_errors.ErrorsConfigInitFail, zap.Error(err))
+	}
+	return ymlC
+}
+
+// clone 拷贝实例并读取新的配置文件，读取失败时仍返回未载入的实例以及读取错误
+func (y *yamlConfig) clone(fileName, cachePrefix string, cacheGen *atomic.Uint64) (*yamlConfig, error) {
+	y.mu.Lock()
+	defer y.mu.Unlock()
 	// 这里存在一个深拷贝，需要注意，避免拷贝的结构体操作对原始结构体造成影响
 	var ymlC = *y
 	var ymlConfViper = *(y.viper)
 	(&ymlC).viper = &ymlConfViper
+	// mu 与原实例共用：viper 结构体的浅拷贝与原实例共享内部的覆盖值、默认值等字典
+	(&ymlC).cachePrefix = cachePrefix
+	(&ymlC).cacheGen = cacheGen
+	(&ymlC).watch = new(watchState)
+	(&ymlC).subscribers = newKeySubscribers()
+	(&ymlC).frozen = newFrozenKeys()
+	(&ymlC).types = newDeclaredTypes()
+	(&ymlC).secrets = newSecretStore(y.secrets.getResolver())
+	(&ymlC).seal = new(sealState)
+	(&ymlC).opts = y.opts.clone()
+	(&ymlC).rules = new(requireRules)
+	(&ymlC).history = newValueHistory()
+	(&ymlC).migrations = newValueMigrations()
+	(&ymlC).providers = newMapProviders()
+	(&ymlC).aliases = newSectionAliases()
+	(&ymlC).roots = new(rootBindings)
+	(&ymlC).latencies = newReadLatencies()
+	(&ymlC).patterns = newKeyPatternCache()
+	(&ymlC).logLevel = new(logLevelBinding)
+	(&ymlC).canary = new(reloadCanary)
+	(&ymlC).changes = newChangeCallbacks()
+	(&ymlC).mergedFiles = nil
+	(&ymlC).merges = newMergeTracking()
+	(&ymlC).embedded = nil
+	(&ymlC).stdin = false
+	(&ymlC).loaded = new(atomic.Bool)
+	(&ymlC).loadedAt = new(atomic.Int64)
 
 	(&ymlC).viper.SetConfigName(fileName)
 	if err := (&ymlC).viper.ReadInConfig(); err != nil {
-		variable.ZapLog.Error(custom_errors.ErrorsConfigInitFail, zap.Error(err))
+		return &ymlC, err
 	}
-	return &ymlC
+	(&ymlC).markLoaded()
+	return &ymlC, nil
 }
 
 // Get 一个原始值
 func (y *yamlConfig) Get(keyName string) interface{} {
-	if y.keyIsCache(keyName) {
-		return y.getValueFromCache(keyName)
-	} else {
-		value := y.viper.Get(keyName)
-		y.cache(keyName, value)
+	if !y.ready(keyName) {
+		return nil
+	}
+	if value, sealed := y.sealedGet(keyName); sealed {
+		return value
+	}
+	if value, exists := containerFactory.KeyIsExists(y.cachePrefix + keyName); exists {
+		return value
+	}
+	gen := y.generation()
+	value := y.viperGet(keyName)
+	if _, isString := value.(string); isString && y.stringProcessed(keyName) {
+		// GetString 读取该键时会转换取值，缓存的原始值会被 GetString 误用，因此不缓存
 		return value
 	}
+	y.cache(gen, keyName, value)
+	return value
 }
 
-// GetString 字符串格式返回值
+// GetString 字符串格式返回值，keyring: 开头的值会通过 SecretResolver 解析，开启 SetSecretFileProfile 后优先读取 *_FILE 指向的密钥文件
+// enc: 开头的加密值通过 SetDecryptor 设置的解密器解密；通过 RegisterValueMigration 登记过的废弃取值会被替换为新值；配置文件 transforms 配置段为该键声明的转换（lowercase、uppercase、trim）在此之前应用
+// 解析失败时记录日志并返回空字符串
 func (y *yamlConfig) GetString(keyName string) string {
-	if y.keyIsCache(keyName) {
-		return y.getValueFromCache(keyName).(string)
+	value, err := y.GetStringE(keyName)
+	if err != nil {
+		logError(custom_errors.ErrorsConfigSecretResolveFail+keyName, zap.Error(err))
+	}
+	return value
+}
+
+// stringProcessed 判断键是否声明了读取转换或登记了取值迁移，此类键 GetString 的结果可能与原始值不同
+func (y *yamlConfig) stringProcessed(keyName string) bool {
+	lowerKey := strings.ToLower(keyName)
+	y.migrations.mu.Lock()
+	_, migrated := y.migrations.mappings[lowerKey]
+	y.migrations.mu.Unlock()
+	return migrated || y.viperGet(transformsKey+"."+lowerKey) != nil
+}
+
+// GetStringE 与 GetString 相同，但会返回密钥引用、密钥文件的解析错误
+func (y *yamlConfig) GetStringE(keyName string) (string, error) {
+	if !y.ready(keyName) {
+		return "", errors.New(custom_errors.ErrorsConfigNotLoaded + keyName)
+	}
+	if filePath, exists := y.secretFilePath(keyName); exists {
+		defer y.recordLatency(keyName, time.Now())
+		return y.secrets.resolveFile(filePath)
+	}
+	var value string
+	if sealedValue, sealed := y.sealedGet(keyName); sealed {
+		value = y.migrateValue(keyName, y.transformValue(keyName, cast.ToString(sealedValue)))
+	} else if cached, ok := y.getValueFromCache(keyName).(string); ok {
+		value = cached
+	} else if cached, ok := y.getValueFromCache(keyName + "#string").(string); ok {
+		value = cached
 	} else {
-		value := y.viper.GetString(keyName)
-		y.cache(keyName, value)
-		return value
+		gen := y.generation()
+		raw := cast.ToString(y.viperGet(keyName))
+		value = y.migrateValue(keyName, y.transformValue(keyName, raw))
+		// 转换或迁移后与原始值不同时使用单独的缓存键，避免 Get 读到转换后的值
+		if value == raw {
+			y.cache(gen, keyName, value)
+		} else {
+			y.cache(gen, keyName+"#string", value)
+		}
+	}
+	if isSecretReference(value) {
+		defer y.recordLatency(keyName, time.Now())
+		return y.secrets.resolve(value)
+	}
+	if isEncryptedValue(value) {
+		defer y.recordLatency(keyName, time.Now())
+		return y.secrets.decrypt(value)
 	}
+	return value, nil
+}
 
+// GetStringChecked 读取字符串后执行校验函数，校验失败时返回校验函数的错误，便于集中校验“必须是合法邮箱”之类的约束
+func (y *yamlConfig) GetStringChecked(keyName string, check func(string) error) (string, error) {
+	value, err := y.GetStringE(keyName)
+	if err != nil {
+		return "", err
+	}
+	if err := check(value); err != nil {
+		return "", err
+	}
+	return value, nil
 }
 
 // GetBool 布尔格式返回值
 func (y *yamlConfig) GetBool(keyName string) bool {
-	if y.keyIsCache(keyName) {
-		return y.getValueFromCache(keyName).(bool)
-	} else {
-		value := y.viper.GetBool(keyName)
-		y.cache(keyName, value)
+	if !y.ready(keyName) {
+		return false
+	}
+	if value, sealed := y.sealedGet(keyName); sealed {
+		return cast.ToBool(value)
+	}
+	if value, ok := y.getValueFromCache(keyName).(bool); ok {
 		return value
 	}
+	gen := y.generation()
+	value := cast.ToBool(y.viperGet(keyName))
+	y.cache(gen, keyName, value)
+	return value
 }
 
 // GetInt 整数格式返回值
 func (y *yamlConfig) GetInt(keyName string) int {
-	if y.keyIsCache(keyName) {
-		return y.getValueFromCache(keyName).(int)
-	} else {
-		value := y.viper.GetInt(keyName)
-		y.cache(keyName, value)
+	if !y.ready(keyName) {
+		return 0
+	}
+	if value, sealed := y.sealedGet(keyName); sealed {
+		return cast.ToInt(trimScalar(value))
+	}
+	if value, ok := y.getValueFromCache(keyName).(int); ok {
 		return value
 	}
+	gen := y.generation()
+	value := cast.ToInt(trimScalar(y.viperGet(keyName)))
+	y.cache(gen, keyName, value)
+	return value
 }
 
 // GetInt32 整数格式返回值
 func (y *yamlConfig) GetInt32(keyName string) int32 {
-	if y.keyIsCache(keyName) {
-		return y.getValueFromCache(keyName).(int32)
-	} else {
-		value := y.viper.GetInt32(keyName)
-		y.cache(keyName, value)
+	if !y.ready(keyName) {
+		return 0
+	}
+	if value, sealed := y.sealedGet(keyName); sealed {
+		return cast.ToInt32(trimScalar(value))
+	}
+	if value, ok := y.getValueFromCache(keyName).(int32); ok {
 		return value
 	}
+	gen := y.generation()
+	value := cast.ToInt32(trimScalar(y.viperGet(keyName)))
+	y.cache(gen, keyName, value)
+	return value
 }
 
 // GetInt64 整数格式返回值
 func (y *yamlConfig) GetInt64(keyName string) int64 {
-	if y.keyIsCache(keyName) {
-		return y.getValueFromCache(keyName).(int64)
-	} else {
-		value := y.viper.GetInt64(keyName)
-		y.cache(keyName, value)
+	if !y.ready(keyName) {
+		return 0
+	}
+	if value, sealed := y.sealedGet(keyName); sealed {
+		return cast.ToInt64(trimScalar(value))
+	}
+	if value, ok := y.getValueFromCache(keyName).(int64); ok {
 		return value
 	}
+	gen := y.generation()
+	value := cast.ToInt64(trimScalar(y.viperGet(keyName)))
+	y.cache(gen, keyName, value)
+	return value
 }
 
 // GetFloat64 小数格式返回值
 func (y *yamlConfig) GetFloat64(keyName string) float64 {
-	if y.keyIsCache(keyName) {
-		return y.getValueFromCache(keyName).(float64)
-	} else {
-		value := y.viper.GetFloat64(keyName)
-		y.cache(keyName, value)
+	if !y.ready(keyName) {
+		return 0
+	}
+	if value, sealed := y.sealedGet(keyName); sealed {
+		return cast.ToFloat64(trimScalar(value))
+	}
+	if value, ok := y.getValueFromCache(keyName).(float64); ok {
 		return value
 	}
+	gen := y.generation()
+	value := cast.ToFloat64(trimScalar(y.viperGet(keyName)))
+	y.cache(gen, keyName, value)
+	return value
 }
 
 // GetDuration 时间单位格式返回值
 func (y *yamlConfig) GetDuration(keyName string) time.Duration {
-	if y.keyIsCache(keyName) {
-		return y.getValueFromCache(keyName).(time.Duration)
-	} else {
-		value := y.viper.GetDuration(keyName)
-		y.cache(keyName, value)
+	if !y.ready(keyName) {
+		return 0
+	}
+	if value, sealed := y.sealedGet(keyName); sealed {
+		return cast.ToDuration(trimScalar(value))
+	}
+	if value, ok := y.getValueFromCache(keyName).(time.Duration); ok {
 		return value
 	}
+	gen := y.generation()
+	value := cast.ToDuration(trimScalar(y.viperGet(keyName)))
+	y.cache(gen, keyName, value)
+	return value
+}
+
+// GetStrictDuration 与 GetDuration 相同，但要求取值带单位（例如 30s、5m、1h30m），不带单位的纯数字返回错误
+// GetDuration 会把 30 解释为 30 纳秒，这是常见的配置错误；键不存在或取值无法解析时同样返回错误
+func (y *yamlConfig) GetStrictDuration(keyName string) (time.Duration, error) {
+	if !y.ready(keyName) {
+		return 0, errors.New(custom_errors.ErrorsConfigNotLoaded + keyName)
+	}
+	return strictDuration(keyName, y.rawGet(keyName))
+}
+
+// strictDuration 将取值解析为带单位的时间
+func strictDuration(keyName string, value interface{}) (time.Duration, error) {
+	if value == nil {
+		return 0, errors.New(custom_errors.ErrorsConfigKeyNotExists + keyName)
+	}
+	str, ok := trimScalar(value).(string)
+	if !ok {
+		return 0, errors.New(custom_errors.ErrorsConfigDurationNoUnit + keyName)
+	}
+	if _, err := strconv.ParseFloat(str, 64); err == nil {
+		return 0, errors.New(custom_errors.ErrorsConfigDurationNoUnit + keyName)
+	}
+	duration, err := time.ParseDuration(str)
+	if err != nil {
+		return 0, fmt.Errorf(custom_errors.ErrorsConfigValueTypeMismatch, keyName, "duration", err.Error())
+	}
+	return duration, nil
 }
 
-// GetStringSlice 字符串切片数格式返回值
+// GetStringSlice 字符串切片数格式返回值，开启 SetStripSliceComments 后去掉元素末尾的 # 注释
 func (y *yamlConfig) GetStringSlice(keyName string) []string {
-	if y.keyIsCache(keyName) {
-		return y.getValueFromCache(keyName).([]string)
-	} else {
-		value := y.viper.GetStringSlice(keyName)
-		y.cache(keyName, value)
+	if !y.ready(keyName) {
+		return nil
+	}
+	if value, sealed := y.sealedGet(keyName); sealed {
+		return y.normalizeStringSlice(cast.ToStringSlice(value))
+	}
+	if value, ok := y.getValueFromCache(keyName).([]string); ok {
+		return value
+	}
+	gen := y.generation()
+	value := y.normalizeStringSlice(cast.ToStringSlice(y.viperGet(keyName)))
+	y.cache(gen, keyName, value)
+	return value
+}
+
+// GetNonEmptyStringSlice 与 GetStringSlice 相同，但结果为空（未配置或配置为空列表）时返回错误，适合“至少一个监听地址”之类的必填列表
+func (y *yamlConfig) GetNonEmptyStringSlice(keyName string) ([]string, error) {
+	if !y.ready(keyName) {
+		return nil, errors.New(custom_errors.ErrorsConfigNotLoaded + keyName)
+	}
+	value := y.GetStringSlice(keyName)
+	if len(value) == 0 {
+		return nil, errors.New(custom_errors.ErrorsConfigEmptySlice + keyName)
+	}
+	return value, nil
+}
+
+// GetStringSet 将字符串切片转为集合，便于 O(1) 判断成员是否存在，重复的元素只保留一个；返回的是缓存值的拷贝
+func (y *yamlConfig) GetStringSet(keyName string) map[string]struct{} {
+	if !y.ready(keyName) {
+		return map[string]struct{}{}
+	}
+	cacheKey := keyName + "#set"
+	if cached, ok := y.getValueFromCache(cacheKey).(map[string]struct{}); ok {
+		return copyStringSet(cached)
+	}
+	gen := y.generation()
+	value := stringSet(y.GetStringSlice(keyName))
+	y.cache(gen, cacheKey, value)
+	return copyStringSet(value)
+}
+
+// stringSet 将字符串切片转为集合
+func stringSet(items []string) map[string]struct{} {
+	value := make(map[string]struct{}, len(items))
+	for _, item := range items {
+		value[item] = struct{}{}
+	}
+	return value
+}
+
+// copyStringSet 拷贝字符串集合
+func copyStringSet(src map[string]struct{}) map[string]struct{} {
+	dst := make(map[string]struct{}, len(src))
+	for key := range src {
+		dst[key] = struct{}{}
+	}
+	return dst
+}
+
+// GetLines 将多行字符串（例如 YAML 的块标量 `|`）按行拆分为切片，每行去掉两端空白并丢弃空行，适合内嵌白名单之类的配置
+func (y *yamlConfig) GetLines(keyName string) []string {
+	if !y.ready(keyName) {
+		return nil
+	}
+	cacheKey := keyName + "#lines"
+	if cached, ok := y.getValueFromCache(cacheKey).([]string); ok {
+		return append([]string(nil), cached...)
+	}
+	gen := y.generation()
+	value := splitLines(y.GetString(keyName))
+	y.cache(gen, cacheKey, value)
+	return append([]string(nil), value...)
+}
+
+// splitLines 按行拆分字符串，每行去掉两端空白并丢弃空行
+func splitLines(str string) []string {
+	value := make([]string, 0)
+	for _, line := range strings.Split(str, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			value = append(value, line)
+		}
+	}
+	return value
+}
+
+// GetGlobSlice 将字符串切片中的每一项作为 glob 模式展开为实际存在的文件路径，例如 ./plugins/*.so
+// 展开结果会被缓存，重新载入后重新展开；没有匹配任何文件的模式会被丢弃并记录告警
+func (y *yamlConfig) GetGlobSlice(keyName string) ([]string, error) {
+	if !y.ready(keyName) {
+		return nil, errors.New(custom_errors.ErrorsConfigNotLoaded + keyName)
+	}
+	cacheKey := keyName + "#glob"
+	if cached, ok := y.getValueFromCache(cacheKey).([]string); ok {
+		return append([]string(nil), cached...), nil
+	}
+	gen := y.generation()
+	value, err := expandGlobs(keyName, y.GetStringSlice(keyName))
+	if err != nil {
+		return nil, err
+	}
+	y.cache(gen, cacheKey, value)
+	return append([]string(nil), value...), nil
+}
+
+// expandGlobs 依次展开 glob 模式，没有匹配任何文件的模式记录告警后丢弃
+func expandGlobs(keyName string, patterns []string) ([]string, error) {
+	value := make([]string, 0)
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) == 0 {
+			logWarn(custom_errors.ErrorsConfigGlobNoMatch+pattern, zap.String("key", keyName))
+			continue
+		}
+		value = append(value, matches...)
+	}
+	return value, nil
+}
+
+// GetPath 读取路径配置，相对路径相对于 variable.BasePath 拼接并规范化，绝对路径原样返回（同样会规范化），未配置时返回空字符串
+func (y *yamlConfig) GetPath(keyName string) string {
+	if !y.ready(keyName) {
+		return ""
+	}
+	cacheKey := keyName + "#path"
+	if cached, ok := y.getValueFromCache(cacheKey).(string); ok {
+		return cached
+	}
+	gen := y.generation()
+	value := resolvePath(y.GetString(keyName))
+	y.cache(gen, cacheKey, value)
+	return value
+}
+
+// resolvePath 相对路径相对于 variable.BasePath 拼接，结果统一规范化，空字符串原样返回
+func resolvePath(value string) string {
+	if value == "" {
+		return value
+	}
+	if !filepath.IsAbs(value) {
+		value = filepath.Join(variable.BasePath, value)
+	}
+	return filepath.Clean(value)
+}
+
+// GetStringMap 字典格式返回值，返回的是缓存值的深拷贝，调用方修改不会影响缓存
+// 字典中含有 _extends 时会合并所继承的配置段，继承链存在循环时记录日志并返回空字典
+func (y *yamlConfig) GetStringMap(keyName string) map[string]interface{} {
+	value, err := y.GetStringMapE(keyName)
+	if err != nil {
+		logError(err.Error())
+		return map[string]interface{}{}
+	}
+	return value
+}
+
+// GetStringMapE 与 GetStringMap 相同，但会返回 _extends 继承链的解析错误
+func (y *yamlConfig) GetStringMapE(keyName string) (map[string]interface{}, error) {
+	if !y.ready(keyName) {
+		return nil, errors.New(custom_errors.ErrorsConfigNotLoaded + keyName)
+	}
+	if y.seal.isSealed() {
+		return y.resolveExtends(keyName, nil)
+	}
+	// 合并了 _extends 的结果与 Get 缓存的原始字典不同，使用单独的缓存键
+	cacheKey := keyName + "#extends"
+	if cached, ok := y.getValueFromCache(cacheKey).(map[string]interface{}); ok {
+		return deepCopyValue(cached).(map[string]interface{}), nil
+	}
+	gen := y.generation()
+	value, err := y.resolveExtends(keyName, nil)
+	if err != nil {
+		return nil, err
+	}
+	y.cache(gen, cacheKey, value)
+	return deepCopyValue(value).(map[string]interface{}), nil
+}
+
+// GetStringMapWithDefaults 将配置段递归合并到默认值之上，配置文件中存在的项优先，只覆盖了部分子项的嵌套配置段同样会补齐其余默认值
+// 默认值的键名按 viper 的规则统一转为小写；合并结果按键名与默认值缓存，返回的是深拷贝
+func (y *yamlConfig) GetStringMapWithDefaults(keyName string, defaults map[string]interface{}) map[string]interface{} {
+	if !y.ready(keyName) {
+		return deepCopyValue(lowerKeys(defaults)).(map[string]interface{})
+	}
+	cacheKey := keyName + "#defaults#" + fmt.Sprint(defaults)
+	if cached, ok := y.getValueFromCache(cacheKey).(map[string]interface{}); ok {
+		return deepCopyValue(cached).(map[string]interface{})
+	}
+	gen := y.generation()
+	value := deepMerge(lowerKeys(defaults), y.GetStringMap(keyName))
+	if !y.seal.isSealed() {
+		y.cache(gen, cacheKey, value)
+	}
+	return deepCopyValue(value).(map[string]interface{})
+}
+
+// GetSectionWithSchema 按 schema 读取配置段：未设置的子键补齐默认值，设置了的子键转换为声明的类型（例如字符串 "8080" 转为 int）
+// schema 的子键为配置段的直接子键，不区分大小写；schema 以外的子键原样保留；转换失败的子键汇总后一并返回错误
+func (y *yamlConfig) GetSectionWithSchema(keyName string, schema yaml_config_interface.Schema) (map[string]interface{}, error) {
+	value := y.GetStringMap(keyName)
+	return value, applySchema(keyName, value, schema)
+}
+
+// applySchema 按 schema 就地补齐默认值并转换类型，返回汇总后的转换错误
+func applySchema(keyName string, value map[string]interface{}, schema yaml_config_interface.Schema) error {
+	subKeys := make([]string, 0, len(schema))
+	for subKey := range schema {
+		subKeys = append(subKeys, subKey)
+	}
+	sort.Strings(subKeys)
+
+	errs := make([]error, 0)
+	for _, subKey := range subKeys {
+		field := schema[subKey]
+		raw, exists := value[strings.ToLower(subKey)]
+		if !exists {
+			if field.Default == nil {
+				continue
+			}
+			raw = field.Default
+		}
+		converted, err := convertValue(keyName+"."+subKey, raw, field.Type)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		value[strings.ToLower(subKey)] = converted
+	}
+	return errors.Join(errs...)
+}
+
+// lowerKeys 递归拷贝字典并将键名转为小写
+func lowerKeys(src map[string]interface{}) map[string]interface{} {
+	dst := make(map[string]interface{}, len(src))
+	for key, val := range src {
+		if subMap, ok := val.(map[string]interface{}); ok {
+			dst[strings.ToLower(key)] = lowerKeys(subMap)
+		} else {
+			dst[strings.ToLower(key)] = deepCopyValue(val)
+		}
+	}
+	return dst
+}
+
+// GetStringMapString 字符串字典格式返回值，返回的是缓存值的拷贝
+// viper 读取字典时不会对子键应用环境变量覆盖，这里逐个子键重新读取，保证 DB_HOST 之类的覆盖生效
+func (y *yamlConfig) GetStringMapString(keyName string) map[string]string {
+	if !y.ready(keyName) {
+		return map[string]string{}
+	}
+	if value, sealed := y.sealedGet(keyName); sealed {
+		return y.normalizeStringMap(cast.ToStringMapString(value))
+	}
+	if cached, ok := y.getValueFromCache(keyName).(map[string]string); ok {
+		return copyStringMap(cached)
+	}
+	gen := y.generation()
+	value := cast.ToStringMapString(y.viperGet(keyName))
+	for subKey := range value {
+		value[subKey] = cast.ToString(y.viperGet(keyName + "." + subKey))
+	}
+	value = y.normalizeStringMap(value)
+	y.cache(gen, keyName, value)
+	return copyStringMap(value)
+}
+
+// GetStringMapStringValidated 读取字符串字典并要求每个值都匹配 pattern（例如全部是 URL），不匹配的子键按名称排序后列在错误中
+// 校验通过的结果按键名与 pattern 缓存，未通过时不缓存，修正配置并重新载入后再次校验
+func (y *yamlConfig) GetStringMapStringValidated(keyName string, pattern *regexp.Regexp) (map[string]string, error) {
+	if !y.ready(keyName) {
+		return nil, errors.New(custom_errors.ErrorsConfigNotLoaded + keyName)
+	}
+	cacheKey := keyName + "#validated#" + pattern.String()
+	if cached, ok := y.getValueFromCache(cacheKey).(map[string]string); ok {
+		return copyStringMap(cached), nil
+	}
+	gen := y.generation()
+	value := y.GetStringMapString(keyName)
+	if err := validateStringMap(keyName, value, pattern); err != nil {
+		return nil, err
+	}
+	if !y.seal.isSealed() {
+		y.cache(gen, cacheKey, value)
+	}
+	return copyStringMap(value), nil
+}
+
+// validateStringMap 检查字典的每个值是否都匹配 pattern，不匹配的子键按名称排序后列在错误中
+func validateStringMap(keyName string, value map[string]string, pattern *regexp.Regexp) error {
+	invalid := make([]string, 0)
+	for subKey, subValue := range value {
+		if !pattern.MatchString(subValue) {
+			invalid = append(invalid, subKey+"="+subValue)
+		}
+	}
+	if len(invalid) == 0 {
+		return nil
+	}
+	sort.Strings(invalid)
+	return fmt.Errorf(custom_errors.ErrorsConfigMapValueMismatch, keyName, pattern.String(), strings.Join(invalid, ", "))
+}
+
+// GetFlatStringMap 将嵌套的配置段展开为一层的字符串字典，键为以 sep 连接的子键路径（sep 为空时使用 .），例如 sep 为 __ 时 Db.Master.Host 展开为 master__host
+// 标量按字符串形式输出，切片编码为 json（与 ExportEnvFile 相同）；空的子配置段不出现在结果中；结果按键名与分隔符缓存
+func (y *yamlConfig) GetFlatStringMap(keyName, sep string) map[string]string {
+	if !y.ready(keyName) {
+		return map[string]string{}
+	}
+	if sep == "" {
+		sep = "."
+	}
+	cacheKey := keyName + "#flat#" + sep
+	if cached, ok := y.getValueFromCache(cacheKey).(map[string]string); ok {
+		return copyStringMap(cached)
+	}
+	gen := y.generation()
+	value := make(map[string]string)
+	flattenStringMap(value, "", sep, y.GetStringMap(keyName))
+	if !y.seal.isSealed() {
+		y.cache(gen, cacheKey, value)
+	}
+	return copyStringMap(value)
+}
+
+// flattenStringMap 递归展开字典，叶子值写入 dst
+func flattenStringMap(dst map[string]string, keyPre, sep string, src map[string]interface{}) {
+	for key, val := range src {
+		if subMap, ok := val.(map[string]interface{}); ok {
+			flattenStringMap(dst, keyPre+key+sep, sep, subMap)
+			continue
+		}
+		if str, err := envFileValue(val); err == nil {
+			dst[keyPre+key] = str
+		}
+	}
+}
+
+// normalizeStringMap 按实例选项规范化字符串字典
+func (y *yamlConfig) normalizeStringMap(value map[string]string) map[string]string {
+	if !y.opts.trimMapStrings.Load() {
+		return value
+	}
+	trimmed := make(map[string]string, len(value))
+	for key, val := range value {
+		trimmed[strings.TrimSpace(key)] = strings.TrimSpace(val)
+	}
+	return trimmed
+}
+
+// GetOrderedMapKeys 按字典序返回字典配置项的键，便于生成顺序稳定的输出
+func (y *yamlConfig) GetOrderedMapKeys(keyName string) []string {
+	return sortedMapKeys(y.GetStringMap(keyName))
+}
+
+// sortedMapKeys 按字典序返回字典的键
+func sortedMapKeys(value map[string]interface{}) []string {
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// GetMapKeys 以列表形式返回字典配置项的键，适合以键表示启用项的配置（例如 Features: {sso: {}, audit: {}}），结果按字典序排序并缓存
+func (y *yamlConfig) GetMapKeys(keyName string) []string {
+	if !y.ready(keyName) {
+		return []string{}
+	}
+	cacheKey := keyName + "#keys"
+	if cached, ok := y.getValueFromCache(cacheKey).([]string); ok {
+		return append([]string(nil), cached...)
+	}
+	gen := y.generation()
+	keys := y.GetOrderedMapKeys(keyName)
+	if !y.seal.isSealed() {
+		y.cache(gen, cacheKey, keys)
+	}
+	return append([]string(nil), keys...)
+}
+
+// GetMapValues 以列表形式返回字典配置项的值，顺序与 GetMapKeys 的键一一对应，结果缓存，返回的是深拷贝
+func (y *yamlConfig) GetMapValues(keyName string) []interface{} {
+	if !y.ready(keyName) {
+		return []interface{}{}
+	}
+	cacheKey := keyName + "#values"
+	if cached, ok := y.getValueFromCache(cacheKey).([]interface{}); ok {
+		return deepCopyValue(cached).([]interface{})
+	}
+	gen := y.generation()
+	values := mapValues(y.GetStringMap(keyName))
+	if !y.seal.isSealed() {
+		y.cache(gen, cacheKey, values)
+	}
+	return deepCopyValue(values).([]interface{})
+}
+
+// mapValues 按键的字典序返回字典的值
+func mapValues(value map[string]interface{}) []interface{} {
+	values := make([]interface{}, 0, len(value))
+	for _, key := range sortedMapKeys(value) {
+		values = append(values, value[key])
+	}
+	return values
+}
+
+// MergeMaps 依次读取多个字符串字典并合并，后面的键覆盖前面的同名子键，合并结果会被缓存
+func (y *yamlConfig) MergeMaps(keys ...string) map[string]string {
+	if !y.ready(strings.Join(keys, ",")) {
+		return map[string]string{}
+	}
+	cacheKey := strings.Join(keys, ",") + "#merged"
+	if cached, ok := y.getValueFromCache(cacheKey).(map[string]string); ok {
+		return copyStringMap(cached)
+	}
+	gen := y.generation()
+	value := make(map[string]string)
+	for _, keyName := range keys {
+		for subKey, subValue := range y.GetStringMapString(keyName) {
+			value[subKey] = subValue
+		}
+	}
+	y.cache(gen, cacheKey, value)
+	return copyStringMap(value)
+}
+
+// GetCIMap 返回一个不区分大小写的查找函数，每次调用都读取缓存中的索引，因此配置重新载入后自动使用新值
+func (y *yamlConfig) GetCIMap(keyName string) func(k string) (string, bool) {
+	cacheKey := keyName + "#ci"
+	return func(k string) (string, bool) {
+		if !y.ready(keyName) {
+			return "", false
+		}
+		index, ok := y.getValueFromCache(cacheKey).(map[string]string)
+		if !ok {
+			gen := y.generation()
+			index = make(map[string]string)
+			for subKey, subValue := range y.GetStringMapString(keyName) {
+				index[strings.ToLower(subKey)] = subValue
+			}
+			y.cache(gen, cacheKey, index)
+		}
+		value, exists := index[strings.ToLower(k)]
+		return value, exists
+	}
+}
+
+// GetStringMapStringStrict 严格模式的字符串字典，任一子项的值不是字符串时返回错误，不做隐式类型转换
+func (y *yamlConfig) GetStringMapStringStrict(keyName string) (map[string]string, error) {
+	if !y.ready(keyName) {
+		return nil, errors.New(custom_errors.ErrorsConfigNotLoaded + keyName)
+	}
+	cacheKey := keyName + "#strict"
+	if cached, ok := y.getValueFromCache(cacheKey).(map[string]string); ok {
+		return copyStringMap(cached), nil
+	}
+	gen := y.generation()
+	value, err := strictStringMap(keyName, y.rawGet(keyName))
+	if err != nil {
+		return nil, err
+	}
+	y.cache(gen, cacheKey, value)
+	return copyStringMap(value), nil
+}
+
+// strictStringMap 要求取值是字典且每个子项都是字符串
+func strictStringMap(keyName string, value interface{}) (map[string]string, error) {
+	raw, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errors.New(custom_errors.ErrorsConfigNotStringMap + keyName)
+	}
+	result := make(map[string]string, len(raw))
+	for subKey, subValue := range raw {
+		str, ok := subValue.(string)
+		if !ok {
+			return nil, errors.New(custom_errors.ErrorsConfigValueNotString + keyName + "." + subKey)
+		}
+		result[subKey] = str
+	}
+	return result, nil
+}
+
+// GetRawSections 将字典配置项的每个子项编码为原始 json，便于原样转发给下游服务
+func (y *yamlConfig) GetRawSections(keyName string) (map[string]json.RawMessage, error) {
+	if !y.ready(keyName) {
+		return nil, errors.New(custom_errors.ErrorsConfigNotLoaded + keyName)
+	}
+	cacheKey := keyName + "#raw"
+	if cached, ok := y.getValueFromCache(cacheKey).(map[string]json.RawMessage); ok {
+		return copyRawSections(cached), nil
+	}
+	gen := y.generation()
+	sections := y.GetStringMap(keyName)
+	value := make(map[string]json.RawMessage, len(sections))
+	for subKey, subValue := range sections {
+		raw, err := json.Marshal(subValue)
+		if err != nil {
+			return nil, err
+		}
+		value[subKey] = raw
+	}
+	y.cache(gen, cacheKey, value)
+	return copyRawSections(value), nil
+}
+
+// copyRawSections 拷贝原始 json 字典，避免调用方修改字节切片影响缓存
+func copyRawSections(src map[string]json.RawMessage) map[string]json.RawMessage {
+	dst := make(map[string]json.RawMessage, len(src))
+	for key, val := range src {
+		dst[key] = append(json.RawMessage(nil), val...)
+	}
+	return dst
+}
+
+// BuildInverted 读取“键 -> 字符串切片”格式的字典并反转为“切片元素 -> 键列表”，例如 角色->权限 反转为 权限->角色
+// 反转结果会被缓存，配置重新载入时随缓存一起清空
+func (y *yamlConfig) BuildInverted(keyName string) map[string][]string {
+	if !y.ready(keyName) {
+		return map[string][]string{}
+	}
+	cacheKey := keyName + "#inverted"
+	if cached, ok := y.getValueFromCache(cacheKey).(map[string][]string); ok {
+		return deepCopyValue(cached).(map[string][]string)
+	}
+	gen := y.generation()
+	value := make(map[string][]string)
+	raw := cast.ToStringMapStringSlice(y.rawGet(keyName))
+	subKeys := make([]string, 0, len(raw))
+	for subKey := range raw {
+		subKeys = append(subKeys, subKey)
+	}
+	sort.Strings(subKeys)
+	for _, subKey := range subKeys {
+		for _, item := range raw[subKey] {
+			value[item] = append(value[item], subKey)
+		}
+	}
+	y.cache(gen, cacheKey, value)
+	return deepCopyValue(value).(map[string][]string)
+}
+
+// InvertMapSlice 与 BuildInverted 相同，例如将 路由->请求方法列表 反转为 请求方法->路由列表，二者共用同一份缓存
+func (y *yamlConfig) InvertMapSlice(keyName string) map[string][]string {
+	return y.BuildInverted(keyName)
+}
+
+// trimScalar 去掉字符串值两端的空白与引号，环境变量中的数值常带有这类多余字符，例如 " 30s " 、"'8080'"
+func trimScalar(value interface{}) interface{} {
+	if str, ok := value.(string); ok {
+		return strings.TrimSpace(strings.Trim(strings.TrimSpace(str), `"'`))
+	}
+	return value
+}
+
+// deepCopyValue 递归拷贝字典、切片类型的值，其余类型原样返回
+func deepCopyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		dst := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			dst[key] = deepCopyValue(val)
+		}
+		return dst
+	case []interface{}:
+		dst := make([]interface{}, len(v))
+		for i, val := range v {
+			dst[i] = deepCopyValue(val)
+		}
+		return dst
+	case map[string]string:
+		return copyStringMap(v)
+	case []string:
+		return append([]string(nil), v...)
+	case map[string][]string:
+		dst := make(map[string][]string, len(v))
+		for key, val := range v {
+			dst[key] = append([]string(nil), val...)
+		}
+		return dst
+	case map[string]struct{}:
+		return copyStringSet(v)
+	case map[string]json.RawMessage:
+		return copyRawSections(v)
+	default:
 		return value
 	}
 }
+
+// copyStringMap 拷贝字符串字典
+func copyStringMap(src map[string]string) map[string]string {
+	dst := make(map[string]string, len(src))
+	for key, val := range src {
+		dst[key] = val
+	}
+	return dst
+}
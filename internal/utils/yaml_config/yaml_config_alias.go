@@ -0,0 +1,55 @@
+package yaml_config
+
+import (
+	"strings"
+	"sync"
+)
+
+// sectionAliases 旧配置段 => 新配置段，键名统一为小写
+type sectionAliases struct {
+	mu      sync.RWMutex
+	aliases map[string]string
+}
+
+func newSectionAliases() *sectionAliases {
+	return &sectionAliases{aliases: make(map[string]string)}
+}
+
+// RegisterSectionAlias 将整个旧配置段映射到新配置段，例如 cache => redis，此后 Cache.Host、GetStringMap("Cache") 等读取都指向 Redis 配置段
+// 用于配置段改名后兼容尚未迁移的调用方；只影响读取，Set 等写入仍按原键名进行
+func (y *yamlConfig) RegisterSectionAlias(oldSection, newSection string) {
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	y.aliases.mu.Lock()
+	y.aliases.aliases[strings.ToLower(oldSection)] = strings.ToLower(newSection)
+	y.aliases.mu.Unlock()
+	// 旧配置段下已缓存、已封存的值需要重新读取
+	y.clearCache()
+	y.reseal()
+}
+
+// resolveAlias 将位于旧配置段下的键名替换为新配置段下的键名，不在任何旧配置段下时原样返回
+func (y *yamlConfig) resolveAlias(keyName string) string {
+	y.aliases.mu.RLock()
+	defer y.aliases.mu.RUnlock()
+	if len(y.aliases.aliases) == 0 {
+		return keyName
+	}
+	lowerKey := strings.ToLower(keyName)
+	for oldSection, newSection := range y.aliases.aliases {
+		if lowerKey == oldSection {
+			return newSection
+		}
+		if strings.HasPrefix(lowerKey, oldSection+".") {
+			return newSection + lowerKey[len(oldSection):]
+		}
+	}
+	return keyName
+}
+
+// hasAliases 是否注册过配置段别名
+func (y *yamlConfig) hasAliases() bool {
+	y.aliases.mu.RLock()
+	defer y.aliases.mu.RUnlock()
+	return len(y.aliases.aliases) > 0
+}
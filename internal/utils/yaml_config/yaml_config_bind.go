@@ -0,0 +1,69 @@
+package yaml_config
+
+import (
+	"apier/internal/global/custom_errors"
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/spf13/cast"
+	"sort"
+)
+
+// 配置结构体的校验器，与表单参数验证器一致使用 binding 标签，例如 `binding:"required,min=1"`
+var configValidator = func() *validator.Validate {
+	v := validator.New()
+	v.SetTagName("binding")
+	return v
+}()
+
+// BindAll 将多个配置段一次性绑定到对应的结构体指针并逐个校验，键为配置段键名，值为目标结构体指针
+// 不会在第一个错误处停止，所有配置段的绑定、校验错误合并后一起返回
+func (y *yamlConfig) BindAll(specs map[string]interface{}) error {
+	if !y.ready("") {
+		return errors.New(custom_errors.ErrorsConfigNotLoaded)
+	}
+	// 按键名排序，保证错误顺序稳定
+	keys := make([]string, 0, len(specs))
+	for keyName := range specs {
+		keys = append(keys, keyName)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, keyName := range keys {
+		target := specs[keyName]
+		y.mu.Lock()
+		err := y.viper.UnmarshalKey(keyName, target)
+		y.mu.Unlock()
+		if err != nil {
+			errs = append(errs, fmt.Errorf(custom_errors.ErrorsConfigBindFail, keyName, err))
+			continue
+		}
+		if err := configValidator.Struct(target); err != nil {
+			errs = append(errs, fmt.Errorf(custom_errors.ErrorsConfigBindFail, keyName, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SectionTypeReport 列出配置段内每个叶子键（相对于配置段的完整键名）解析后的 Go 类型，例如 port => int、timeout => string
+// 用于排查 BindAll 绑定失败的原因：YAML 中带引号的数字是 string，列表是 []interface {}
+func (y *yamlConfig) SectionTypeReport(keyName string) map[string]string {
+	report := make(map[string]string)
+	if !y.ready(keyName) {
+		return report
+	}
+	reportLeafTypes(cast.ToStringMap(y.viperGet(keyName)), "", report)
+	return report
+}
+
+// reportLeafTypes 递归记录叶子键的类型，字典视为配置段继续向下展开
+func reportLeafTypes(settings map[string]interface{}, keyPre string, report map[string]string) {
+	for key, value := range settings {
+		if subSettings, ok := value.(map[string]interface{}); ok {
+			reportLeafTypes(subSettings, keyPre+key+".", report)
+			continue
+		}
+		report[keyPre+key] = fmt.Sprintf("%T", value)
+	}
+}
@@ -0,0 +1,49 @@
+package yaml_config
+
+import (
+	"apier/internal/utils/yaml_config/yaml_config_interface"
+	"sync"
+)
+
+// reloadCanary SetReloadCanary 设置的检查函数
+type reloadCanary struct {
+	mu sync.Mutex
+	fn func(candidate yaml_config_interface.YamlConfigInterface) error
+}
+
+func (c *reloadCanary) get() func(candidate yaml_config_interface.YamlConfigInterface) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fn
+}
+
+// SetReloadCanary 设置重新载入前的检查函数，例如用新配置试连数据库；fn 为 nil 表示取消
+// 每次 Reload 先用新的配置文件构建一个临时实例交给 fn，fn 返回错误时放弃本次重新载入、保留旧配置，Reload 返回该错误
+// 临时实例只在 fn 执行期间有效；fn 执行时持有原实例的锁，因此 fn 中只能读取 candidate，不能调用原实例的方法
+func (y *yamlConfig) SetReloadCanary(fn func(candidate yaml_config_interface.YamlConfigInterface) error) {
+	y.canary.mu.Lock()
+	defer y.canary.mu.Unlock()
+	y.canary.fn = fn
+}
+
+// loadCandidate 按与 Reload 相同的步骤（内嵌默认配置、主配置文件、MergeConfig 合并的文件）读取配置到一个临时实例，调用方需持有 y.mu
+// 临时实例的 viper 是原实例 viper 的浅拷贝，共用环境变量、命令行参数、Set 的覆盖值，检查通过后原实例直接采用该 viper 的内容
+func (y *yamlConfig) loadCandidate() (*yamlConfig, error) {
+	candidate := newYamlConfig("")
+	candidateViper := *(y.viper)
+	candidate.viper = &candidateViper
+	candidate.embedded = y.embedded
+	candidate.stdin = y.stdin
+	candidate.mergedFiles = y.mergedFiles
+	candidate.aliases = y.aliases
+	candidate.opts = y.opts.clone()
+	candidate.secrets = y.secrets.cloneSettings()
+	if err := candidate.readConfig(); err != nil {
+		return nil, err
+	}
+	if err := candidate.applyMergedFiles(); err != nil {
+		return nil, err
+	}
+	candidate.markLoaded()
+	return candidate, nil
+}
@@ -0,0 +1,123 @@
+package yaml_config
+
+import (
+	"apier/internal/global/custom_errors"
+	"apier/internal/utils/yaml_config/yaml_config_interface"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"go.uber.org/zap"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+)
+
+// changeCallbacks OnChange 登记的回调
+type changeCallbacks struct {
+	mu        sync.Mutex
+	nextId    int
+	callbacks map[int]func(event yaml_config_interface.ReloadEvent)
+	checksum  string // 最近一次载入的配置文件内容摘要，由 y.mu 保护
+}
+
+func newChangeCallbacks() *changeCallbacks {
+	return &changeCallbacks{callbacks: make(map[int]func(event yaml_config_interface.ReloadEvent))}
+}
+
+// changeCapture 重新载入之前的配置快照
+type changeCapture struct {
+	checksum string
+	settings map[string]interface{}
+}
+
+// OnChange 登记配置重新载入后的回调，每次 Reload、ReloadMergedFile 成功后调用一次，参数包含前后的配置摘要、键的差异与时间
+// 摘要是读取的配置文件内容（内嵌默认配置、主配置文件、合并的文件）的 sha256，只改注释、空白的编辑同样会改变摘要；环境变量、命令行参数的覆盖不计入摘要，这一点与 ConfigChecksum 不同
+// 回调在释放实例的锁之后同步执行，可以在回调中读取配置；调用返回的函数取消登记
+func (y *yamlConfig) OnChange(fn func(event yaml_config_interface.ReloadEvent)) func() {
+	y.mu.Lock()
+	y.changes.mu.Lock()
+	if len(y.changes.callbacks) == 0 && y.viper != nil && y.loaded.Load() {
+		// 没有回调时重新载入不记录摘要，登记第一个回调时重新计算
+		y.changes.checksum = y.contentChecksum()
+	}
+	id := y.changes.nextId
+	y.changes.nextId++
+	y.changes.callbacks[id] = fn
+	y.changes.mu.Unlock()
+	y.mu.Unlock()
+
+	return func() {
+		y.changes.mu.Lock()
+		defer y.changes.mu.Unlock()
+		delete(y.changes.callbacks, id)
+	}
+}
+
+// beginChange 记录重新载入之前的配置，没有登记回调时返回 nil，调用方需持有 y.mu
+func (y *yamlConfig) beginChange() *changeCapture {
+	y.changes.mu.Lock()
+	registered := len(y.changes.callbacks) > 0
+	y.changes.mu.Unlock()
+	if !registered {
+		return nil
+	}
+	// 文件此时可能已经被改写，旧摘要取上一次载入时记录的值
+	return &changeCapture{checksum: y.changes.checksum, settings: flattenSettings(y.viper)}
+}
+
+// endChange 对比重新载入前后的配置生成事件，调用方需持有 y.mu
+func (y *yamlConfig) endChange(capture *changeCapture) *yaml_config_interface.ReloadEvent {
+	if capture == nil {
+		return nil
+	}
+	y.changes.checksum = y.contentChecksum()
+	return &yaml_config_interface.ReloadEvent{
+		OldChecksum: capture.checksum,
+		NewChecksum: y.changes.checksum,
+		Changes:     diffSettings(capture.settings, flattenSettings(y.viper)),
+		Time:        time.Now(),
+	}
+}
+
+// contentChecksum 依次对内嵌默认配置、主配置文件与合并的文件的字节计算 sha256，不存在的文件跳过，调用方需持有 y.mu
+// 文件在重新载入之后才读取，读取期间文件再次被改写时摘要对应的是改写后的内容，随后的重新载入会再次更新
+func (y *yamlConfig) contentChecksum() string {
+	hash := sha256.New()
+	hash.Write(y.embedded)
+	files := make([]string, 0, len(y.mergedFiles)+1)
+	if configFile := y.viper.ConfigFileUsed(); !y.stdin && configFile != "" {
+		files = append(files, configFile)
+	}
+	for _, fileName := range y.mergedFiles {
+		filePath, _ := configFilePath(fileName)
+		files = append(files, filePath)
+	}
+	for _, filePath := range files {
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				logWarn(custom_errors.ErrorsConfigChecksumFail, zap.String("file", filePath), zap.Error(err))
+			}
+			continue
+		}
+		hash.Write(content)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// dispatchChange 依次调用登记的回调，调用方不能持有 y.mu
+func (y *yamlConfig) dispatchChange(event *yaml_config_interface.ReloadEvent) {
+	if event == nil {
+		return
+	}
+	y.changes.mu.Lock()
+	callbacks := make([]func(event yaml_config_interface.ReloadEvent), 0, len(y.changes.callbacks))
+	for _, fn := range y.changes.callbacks {
+		callbacks = append(callbacks, fn)
+	}
+	y.changes.mu.Unlock()
+	for _, fn := range callbacks {
+		fn(*event)
+	}
+}
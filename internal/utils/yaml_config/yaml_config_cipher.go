@@ -0,0 +1,97 @@
+package yaml_config
+
+import (
+	"apier/internal/global/custom_errors"
+	"apier/internal/utils/yaml_config/yaml_config_interface"
+	"errors"
+	"github.com/spf13/viper"
+	"strings"
+)
+
+// 加密配置值的前缀，完整格式：enc:密文
+const encryptedValuePrefix = "enc:"
+
+func isEncryptedValue(value string) bool {
+	return strings.HasPrefix(value, encryptedValuePrefix)
+}
+
+// SetDecryptor 设置解密器，此后 GetString 读到 enc: 开头的值时返回解密后的明文，已解密的值随之失效
+func (y *yamlConfig) SetDecryptor(decryptor yaml_config_interface.Decryptor) {
+	y.secrets.mu.Lock()
+	defer y.secrets.mu.Unlock()
+	y.secrets.decryptor = decryptor
+	y.secrets.values = make(map[string]string)
+}
+
+// SetEncryptor 设置加密器，WriteConfigAs 写出配置时用它重新加密敏感项
+func (y *yamlConfig) SetEncryptor(encryptor yaml_config_interface.Encryptor) {
+	y.secrets.mu.Lock()
+	defer y.secrets.mu.Unlock()
+	y.secrets.encryptor = encryptor
+}
+
+// decrypt 解密 enc: 开头的值，明文与 keyring 引用一样只缓存在实例内存中，失败不缓存
+func (s *secretStore) decrypt(value string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if plaintext, exists := s.values[value]; exists {
+		return plaintext, nil
+	}
+	if s.decryptor == nil {
+		return "", errors.New(custom_errors.ErrorsConfigNoDecryptor + value)
+	}
+	plaintext, err := s.decryptor.Decrypt(strings.TrimPrefix(value, encryptedValuePrefix))
+	if err != nil {
+		return "", err
+	}
+	s.values[value] = plaintext
+	return plaintext, nil
+}
+
+// WriteConfigAs 将当前生效的全部配置（包括 Set 修改过的值）写入文件，文件格式按扩展名识别
+// 敏感项（判断规则与 AllSettingsMasked 相同）中的明文字符串会先用 SetEncryptor 设置的加密器加密为 enc: 形式，保证写出的快照中没有明文密钥
+// 存在需要加密的明文敏感项、但没有设置加密器时返回错误，不写出文件
+func (y *yamlConfig) WriteConfigAs(filePath string) error {
+	y.mu.Lock()
+	settings := y.viper.AllSettings()
+	y.mu.Unlock()
+
+	y.secrets.mu.Lock()
+	encryptor := y.secrets.encryptor
+	y.secrets.mu.Unlock()
+	if err := encryptSettings(settings, "", false, encryptor); err != nil {
+		return err
+	}
+
+	snapshot := viper.New()
+	if err := snapshot.MergeConfigMap(settings); err != nil {
+		return err
+	}
+	return snapshot.WriteConfigAs(filePath)
+}
+
+// encryptSettings 递归加密敏感项中的明文字符串，敏感配置段下的全部子项都视为敏感项，已经是 enc: 形式的值保持不变
+func encryptSettings(settings map[string]interface{}, keyPre string, sensitive bool, encryptor yaml_config_interface.Encryptor) error {
+	for key, value := range settings {
+		keySensitive := sensitive || isSensitiveKey(key)
+		if subSettings, ok := value.(map[string]interface{}); ok {
+			if err := encryptSettings(subSettings, keyPre+key+".", keySensitive, encryptor); err != nil {
+				return err
+			}
+			continue
+		}
+		plaintext, ok := value.(string)
+		if !keySensitive || !ok || plaintext == "" || isEncryptedValue(plaintext) || isSecretReference(plaintext) {
+			continue
+		}
+		if encryptor == nil {
+			return errors.New(custom_errors.ErrorsConfigNoEncryptor + keyPre + key)
+		}
+		ciphertext, err := encryptor.Encrypt(plaintext)
+		if err != nil {
+			return err
+		}
+		settings[key] = encryptedValuePrefix + ciphertext
+	}
+	return nil
+}
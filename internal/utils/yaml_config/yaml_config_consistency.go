@@ -0,0 +1,72 @@
+package yaml_config
+
+import (
+	"apier/internal/global/custom_errors"
+	"fmt"
+	"github.com/spf13/viper"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// CheckTypeConsistency 分别读取多个配置文件（例如 config.yml 与 config.prod.yml），找出同一个键在不同文件中类型不一致的情况
+// 例如一个文件中 Port: 8080 为 int，另一个文件中 Port: "8080" 为 string，合并后取决于文件顺序，容易引起隐蔽的问题
+// 文件路径规则与 MergeConfig 相同；只对比两个以上文件中都存在的叶子键，结果按键名排序
+func (y *yamlConfig) CheckTypeConsistency(files ...string) []error {
+	errs := make([]error, 0)
+	types := make(map[string][]string) // 键 => 每个文件中的 文件名=类型
+	for _, fileName := range files {
+		fileConfig, err := readConfigFile(fileName)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for keyName, value := range flattenSettings(fileConfig) {
+			types[keyName] = append(types[keyName], fmt.Sprintf("%s=%T", fileName, value))
+		}
+	}
+
+	keys := make([]string, 0, len(types))
+	for keyName := range types {
+		keys = append(keys, keyName)
+	}
+	sort.Strings(keys)
+	for _, keyName := range keys {
+		fileTypes := types[keyName]
+		for _, fileType := range fileTypes[1:] {
+			if typeOfFileType(fileType) != typeOfFileType(fileTypes[0]) {
+				errs = append(errs, fmt.Errorf(custom_errors.ErrorsConfigTypeInconsistent, keyName, strings.Join(fileTypes, ", ")))
+				break
+			}
+		}
+	}
+	return errs
+}
+
+// typeOfFileType 取出 文件名=类型 中的类型部分
+func typeOfFileType(fileType string) string {
+	return fileType[strings.LastIndex(fileType, "=")+1:]
+}
+
+// readConfigFile 单独读取一个配置文件，文件类型按扩展名识别，没有扩展名时按 yml 处理
+func readConfigFile(fileName string) (*viper.Viper, error) {
+	filePath, configType := configFilePath(fileName)
+	fileConfig := viper.New()
+	fileConfig.SetConfigFile(filePath)
+	fileConfig.SetConfigType(configType)
+	if err := fileConfig.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	return fileConfig, nil
+}
+
+// configFilePath 返回 readConfigFile 实际读取的文件路径与文件类型
+func configFilePath(fileName string) (string, string) {
+	filePath := resolveConfigPath(fileName)
+	configType := strings.TrimPrefix(filepath.Ext(filePath), ".")
+	if configType == "" {
+		configType = "yml"
+		filePath += ".yml"
+	}
+	return filePath, configType
+}
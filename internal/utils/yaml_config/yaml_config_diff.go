@@ -0,0 +1,140 @@
+package yaml_config
+
+import (
+	"apier/internal/global/custom_errors"
+	"apier/internal/utils/yaml_config/yaml_config_interface"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// DiffAgainstFile 将当前配置与 configs 目录下的参考文件对比，用于检测线上配置是否偏离了提交的版本
+// 以参考文件为基准：当前配置多出的键为 added，缺少的键为 removed，值不同的键为 changed
+func (y *yamlConfig) DiffAgainstFile(fileName string) ([]yaml_config_interface.ChangedKey, error) {
+	reference := newYamlConfig(fileName)
+	if err := reference.viper.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	return diffSettings(flattenSettings(reference.viper), flattenSettings(y.viper)), nil
+}
+
+// AllSettingsFlattened 以点号分隔的完整键名（小写）返回全部叶子配置项，值未脱敏
+func (y *yamlConfig) AllSettingsFlattened() map[string]interface{} {
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	return flattenSettings(y.viper)
+}
+
+// flattenSettings 以点号分隔的完整键名展开全部配置
+func flattenSettings(v *viper.Viper) map[string]interface{} {
+	settings := make(map[string]interface{})
+	for _, keyName := range v.AllKeys() {
+		settings[keyName] = v.Get(keyName)
+	}
+	return settings
+}
+
+// diffSettings 对比两份展开后的配置，结果按键名排序
+func diffSettings(oldSettings, newSettings map[string]interface{}) []yaml_config_interface.ChangedKey {
+	changes := make([]yaml_config_interface.ChangedKey, 0)
+	for keyName, oldValue := range oldSettings {
+		newValue, exists := newSettings[keyName]
+		if !exists {
+			changes = append(changes, yaml_config_interface.ChangedKey{Key: keyName, Type: yaml_config_interface.KeyRemoved, OldValue: oldValue})
+		} else if !reflect.DeepEqual(oldValue, newValue) {
+			changes = append(changes, yaml_config_interface.ChangedKey{Key: keyName, Type: yaml_config_interface.KeyChanged, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+	for keyName, newValue := range newSettings {
+		if _, exists := oldSettings[keyName]; !exists {
+			changes = append(changes, yaml_config_interface.ChangedKey{Key: keyName, Type: yaml_config_interface.KeyAdded, NewValue: newValue})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
+
+// ConfigChecksum 返回全部生效配置的 sha256 摘要，供轮询配置的客户端低成本判断配置是否变化
+// 配置按 MarshalStable 的规则规范化数值后以 json 编码计算，字典按键名排序、键名统一为小写，因此相同的配置在不同进程、多次重启之间得到相同的摘要，8080 与 8080.0 也得到相同的摘要
+func (y *yamlConfig) ConfigChecksum() string {
+	y.mu.Lock()
+	settings := y.viper.AllSettings()
+	y.mu.Unlock()
+	return settingsChecksum(settings)
+}
+
+// settingsChecksum 配置规范化数值并以 json 编码后计算 sha256 摘要，失败时记录日志并返回空字符串
+func settingsChecksum(settings map[string]interface{}) string {
+	content, err := json.Marshal(normalizeNumbers(settings))
+	if err != nil {
+		logError(custom_errors.ErrorsConfigChecksumFail, zap.Error(err))
+		return ""
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// MarshalStable 将全部生效配置编码为规范化的 json，用于 golden 文件对比
+// 字典按键名排序、键名统一为小写，数值统一为最短的十进制表示（8080 与 8080.0 的结果相同），因此同一份配置在不同机器、多次运行之间得到完全相同的字节
+func (y *yamlConfig) MarshalStable() ([]byte, error) {
+	y.mu.Lock()
+	settings := y.viper.AllSettings()
+	y.mu.Unlock()
+	return json.MarshalIndent(normalizeNumbers(settings), "", "  ")
+}
+
+// normalizeNumbers 递归地将各种整数、浮点数统一转换为 json.Number，NaN、Inf 等无法转换的浮点数原样保留
+func normalizeNumbers(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		normalized := make(map[string]interface{}, len(typed))
+		for key, val := range typed {
+			normalized[key] = normalizeNumbers(val)
+		}
+		return normalized
+	case []interface{}:
+		normalized := make([]interface{}, len(typed))
+		for i, val := range typed {
+			normalized[i] = normalizeNumbers(val)
+		}
+		return normalized
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return json.Number(strconv.FormatInt(rv.Int(), 10))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return json.Number(strconv.FormatUint(rv.Uint(), 10))
+	case reflect.Float32, reflect.Float64:
+		if f := rv.Float(); !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return json.Number(strconv.FormatFloat(f, 'f', -1, 64))
+		}
+	}
+	return value
+}
+
+// DiffConfigs 对比两个配置实例展开后的全部键，返回 b 相对于 a 新增、删除、变化的键，按键名排序，适合在测试中断言重新载入的效果
+// 数值按 normalizeNumbers 规范化后比较，int 8080 与 float64 8080 视为相同；结果中的 OldValue、NewValue 为各自实例中的原始值
+func DiffConfigs(a, b yaml_config_interface.YamlConfigInterface) []yaml_config_interface.ChangedKey {
+	oldSettings, newSettings := a.AllSettingsFlattened(), b.AllSettingsFlattened()
+	changes := diffSettings(normalizeNumbers(oldSettings).(map[string]interface{}), normalizeNumbers(newSettings).(map[string]interface{}))
+	for i := range changes {
+		if changes[i].Type != yaml_config_interface.KeyAdded {
+			changes[i].OldValue = oldSettings[changes[i].Key]
+		}
+		if changes[i].Type != yaml_config_interface.KeyRemoved {
+			changes[i].NewValue = newSettings[changes[i].Key]
+		}
+	}
+	return changes
+}
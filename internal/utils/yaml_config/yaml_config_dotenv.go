@@ -0,0 +1,122 @@
+package yaml_config
+
+import (
+	"apier/internal/global/custom_errors"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 不需要加引号的环境变量值
+var plainEnvValue = regexp.MustCompile(`^[A-Za-z0-9_./:@,+-]*$`)
+
+// ExportEnvFile 将全部配置以 前缀_键名=值 的形式逐行写入 .env 文件，键名规则与 AutomaticEnv 相同，便于在 shell 脚本中 source 使用
+// 字典、切片类型的值编码为 json，含有空格等特殊字符的值使用单引号包裹；值未脱敏，需要脱敏请使用 ExportEnvFileMasked
+func (y *yamlConfig) ExportEnvFile(filePath, prefix string) error {
+	return y.exportEnvFile(filePath, prefix, false)
+}
+
+// ExportEnvFileMasked 与 ExportEnvFile 相同，但敏感项（判断规则与 AllSettingsMasked 相同）的值被替换为占位值
+func (y *yamlConfig) ExportEnvFileMasked(filePath, prefix string) error {
+	return y.exportEnvFile(filePath, prefix, true)
+}
+
+func (y *yamlConfig) exportEnvFile(filePath, prefix string, mask bool) error {
+	settings := y.AllSettingsFlattened()
+	keys := make([]string, 0, len(settings))
+	for keyName := range settings {
+		keys = append(keys, keyName)
+	}
+	sort.Strings(keys)
+
+	var builder strings.Builder
+	for _, keyName := range keys {
+		value, err := envFileValue(settings[keyName])
+		if err != nil {
+			return err
+		}
+		if mask {
+			for _, segment := range strings.Split(keyName, ".") {
+				if isSensitiveKey(segment) {
+					value = maskedValue
+					break
+				}
+			}
+		}
+		builder.WriteString(envKeyName(prefix, keyName) + "=" + quoteEnvValue(value) + "\n")
+	}
+	return os.WriteFile(filePath, []byte(builder.String()), 0600)
+}
+
+// envFileValue 标量按字符串形式输出，字典、切片编码为 json
+func envFileValue(value interface{}) (string, error) {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}, []string, map[string]string:
+		content, err := json.Marshal(value)
+		return string(content), err
+	case nil:
+		return "", nil
+	default:
+		return fmt.Sprint(value), nil
+	}
+}
+
+// quoteEnvValue 含有特殊字符的值使用单引号包裹，值中的单引号按 shell 的规则先结束引号、转义后再重新开始引号，例如：
+//
+//	it's => 'it'\''s'
+func quoteEnvValue(value string) string {
+	if plainEnvValue.MatchString(value) {
+		return value
+	}
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// LoadDotEnv 读取 .env 文件并设置为当前进程的环境变量，已经存在的环境变量不会被覆盖，配合 AutomaticEnv 即可覆盖配置文件中的值
+// 支持空行、# 注释、export 前缀，以及单引号、双引号包裹的值；应在创建配置实例、读取配置之前调用
+func LoadDotEnv(filePath string) error {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		envName, value, found := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		envName = strings.TrimSpace(envName)
+		if !found || envName == "" {
+			return fmt.Errorf(custom_errors.ErrorsDotEnvLineInvalid, filePath, lineNo, line)
+		}
+		value, err := unquoteEnvValue(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf(custom_errors.ErrorsDotEnvLineInvalid, filePath, lineNo, line)
+		}
+		if _, exists := os.LookupEnv(envName); exists {
+			continue
+		}
+		if err := os.Setenv(envName, value); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// unquoteEnvValue 去掉值两端的引号
+func unquoteEnvValue(value string) (string, error) {
+	if len(value) >= 2 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'") {
+		return strings.ReplaceAll(value[1:len(value)-1], `'\''`, "'"), nil
+	}
+	if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+		return strconv.Unquote(value)
+	}
+	return value, nil
+}
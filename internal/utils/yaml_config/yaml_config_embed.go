@@ -0,0 +1,52 @@
+package yaml_config
+
+import (
+	"apier/internal/global/custom_errors"
+	"apier/internal/utils/yaml_config/yaml_config_interface"
+	"bytes"
+	"errors"
+	"github.com/spf13/viper"
+	"log"
+)
+
+// CreateYamlFactoryWithDefaults 以编译进程序的默认配置为基础创建配置实例，configs 目录下存在同名文件时再将其合并到默认配置之上
+// 适合单文件分发的工具，defaults 通常来自 go:embed，例如：
+//
+//	//go:embed config.yml
+//	var defaultConfig []byte
+//
+// 外部文件可以只覆盖部分键，也可以新增键；外部文件不存在时只使用默认配置，重新载入时同样先读取默认配置再合并外部文件
+func CreateYamlFactoryWithDefaults(defaults []byte, fileName ...string) yaml_config_interface.YamlConfigInterface {
+	name := "config"
+	if len(fileName) > 0 {
+		name = fileName[0]
+	}
+
+	ymlConfig := newYamlConfig(name)
+	ymlConfig.embedded = append([]byte(nil), defaults...)
+	if err := ymlConfig.readConfig(); err != nil {
+		log.Fatal(custom_errors.ErrorsConfigInitFail + err.Error())
+	}
+	ymlConfig.markLoaded()
+	return ymlConfig
+}
+
+// readConfig 读取配置文件，设置了内嵌默认配置时先读取默认配置，再合并可能不存在的外部文件；来自标准输入的配置无法再次读取，调用方需持有 y.mu
+func (y *yamlConfig) readConfig() error {
+	if y.stdin {
+		return errors.New(custom_errors.ErrorsConfigStdinNoReload)
+	}
+	if y.embedded == nil {
+		return y.viper.ReadInConfig()
+	}
+	if err := y.viper.ReadConfig(bytes.NewReader(y.embedded)); err != nil {
+		return err
+	}
+	if err := y.viper.MergeInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if !errors.As(err, &notFound) {
+			return err
+		}
+	}
+	return nil
+}
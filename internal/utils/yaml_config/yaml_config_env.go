@@ -0,0 +1,119 @@
+package yaml_config
+
+import (
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 可以在 shell 中设置的环境变量名：字母、数字与下划线，且不以数字开头
+var envNamePattern = regexp.MustCompile(`^[A-Z_][A-Z0-9_]*$`)
+
+// envKeyName 按 AutomaticEnv 的规则计算键对应的环境变量名：. 替换为 _ 并转大写，再加上前缀
+func envKeyName(envPrefix, keyName string) string {
+	envName := strings.ToUpper(strings.ReplaceAll(keyName, ".", "_"))
+	if envPrefix != "" {
+		envName = strings.ToUpper(envPrefix) + "_" + envName
+	}
+	return envName
+}
+
+// UnmatchedEnvVars 列出带有指定前缀、但不对应任何已知配置键的环境变量，用于发现拼写错误而悄悄失效的覆盖
+// *_FILE 形式的密钥文件变量按去掉 _FILE 后的名称匹配
+func (y *yamlConfig) UnmatchedEnvVars(prefix string) []string {
+	known := make(map[string]struct{})
+	y.mu.Lock()
+	allKeys := y.viper.AllKeys()
+	y.mu.Unlock()
+	for _, keyName := range allKeys {
+		known[envKeyName(prefix, keyName)] = struct{}{}
+	}
+	envPrefix := strings.ToUpper(prefix) + "_"
+	unmatched := make([]string, 0)
+	for _, env := range os.Environ() {
+		envName, _, _ := strings.Cut(env, "=")
+		if !strings.HasPrefix(envName, envPrefix) {
+			continue
+		}
+		if _, exists := known[envName]; exists {
+			continue
+		}
+		if _, exists := known[strings.TrimSuffix(envName, "_FILE")]; exists {
+			continue
+		}
+		unmatched = append(unmatched, envName)
+	}
+	sort.Strings(unmatched)
+	return unmatched
+}
+
+// AuditEnvCoverage 列出无法通过 AutomaticEnv 覆盖的配置键：按 envKeyName 的规则只替换 . 为 _，键名中含有 -、空格等字符时得到的环境变量名无法在 shell 中设置
+// 只检查从配置文件（含内嵌默认配置与合并的文件）读取的键，默认值、命令行参数、环境变量与 Set 设置的键不在检查范围内
+// 前缀取 AutomaticEnv 设置的值，结果为小写的完整键名并按键名排序
+func (y *yamlConfig) AuditEnvCoverage() []string {
+	y.mu.Lock()
+	envPrefix := y.viper.GetEnvPrefix()
+	fileKeys := make([]string, 0)
+	for _, keyName := range y.viper.AllKeys() {
+		if y.viper.InConfig(keyName) {
+			fileKeys = append(fileKeys, keyName)
+		}
+	}
+	y.mu.Unlock()
+	unreachable := make([]string, 0)
+	for _, keyName := range fileKeys {
+		if !envNamePattern.MatchString(envKeyName(envPrefix, keyName)) {
+			unreachable = append(unreachable, keyName)
+		}
+	}
+	sort.Strings(unreachable)
+	return unreachable
+}
+
+// GetStringMapStringEnvFallback 读取字符串字典，expectedKeys 中配置文件未设置的子键改为读取环境变量 envPrefix_子键（规则同 envKeyName）
+// 例如 envPrefix 为 APIER_REDIS 时，缺少的 Password 子键读取 APIER_REDIS_PASSWORD；两处都没有的子键不出现在结果中，子键统一为小写
+func (y *yamlConfig) GetStringMapStringEnvFallback(keyName string, expectedKeys []string, envPrefix string) map[string]string {
+	value := y.GetStringMapString(keyName)
+	for _, subKey := range expectedKeys {
+		subKey = strings.ToLower(subKey)
+		if _, exists := value[subKey]; exists {
+			continue
+		}
+		if envValue, exists := os.LookupEnv(envKeyName(envPrefix, subKey)); exists {
+			value[subKey] = envValue
+		}
+	}
+	return value
+}
+
+// GetStringMapStringWithEnv 将字符串字典与 envVars 中列出的操作系统环境变量合并，常用于拼装传给子进程的环境
+// 环境变量以小写名称作为子键（与配置文件的子键规则一致），envWins 为 true 时同名子键以环境变量为准，否则以配置文件为准；未设置的环境变量忽略
+// 合并结果按参数缓存，之后环境变量的变化在重新载入配置前不会生效
+func (y *yamlConfig) GetStringMapStringWithEnv(keyName string, envVars []string, envWins bool) map[string]string {
+	if !y.ready(keyName) {
+		return map[string]string{}
+	}
+	cacheKey := keyName + "#env#" + strings.Join(envVars, ",") + "#" + strconv.FormatBool(envWins)
+	if cached, ok := y.getValueFromCache(cacheKey).(map[string]string); ok {
+		return copyStringMap(cached)
+	}
+	gen := y.generation()
+	value := y.GetStringMapString(keyName)
+	for _, envName := range envVars {
+		envValue, exists := os.LookupEnv(envName)
+		if !exists {
+			continue
+		}
+		subKey := strings.ToLower(envName)
+		if _, configured := value[subKey]; configured && !envWins {
+			continue
+		}
+		value[subKey] = envValue
+	}
+	if !y.seal.isSealed() {
+		y.cache(gen, cacheKey, value)
+	}
+	return copyStringMap(value)
+}
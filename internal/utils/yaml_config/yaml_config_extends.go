@@ -0,0 +1,53 @@
+package yaml_config
+
+import (
+	"apier/internal/global/custom_errors"
+	"errors"
+	"github.com/spf13/cast"
+	"strings"
+)
+
+// 配置段继承所使用的键，例如 `Prod: {_extends: _base, Port: 80}`，值为被继承配置段的完整键名
+const extendsKey = "_extends"
+
+// resolveExtends 读取配置段并沿 _extends 链向上合并，子配置段的值覆盖父配置段，chain 记录已经经过的配置段用于检测循环
+func (y *yamlConfig) resolveExtends(keyName string, chain []string) (map[string]interface{}, error) {
+	// viper 返回的是内部字典，必须先拷贝再修改
+	var section map[string]interface{}
+	if value, sealed := y.sealedGet(keyName); sealed {
+		section = deepCopyValue(cast.ToStringMap(value)).(map[string]interface{})
+	} else {
+		section = deepCopyValue(cast.ToStringMap(y.viperGet(keyName))).(map[string]interface{})
+	}
+	parent, ok := section[extendsKey].(string)
+	if !ok {
+		return section, nil
+	}
+	delete(section, extendsKey)
+
+	chain = append(chain, strings.ToLower(keyName))
+	for _, visited := range chain {
+		if visited == strings.ToLower(parent) {
+			return nil, errors.New(custom_errors.ErrorsConfigExtendsCycle + strings.Join(append(chain, strings.ToLower(parent)), " -> "))
+		}
+	}
+	base, err := y.resolveExtends(parent, chain)
+	if err != nil {
+		return nil, err
+	}
+	return deepMerge(base, section), nil
+}
+
+// deepMerge 将 override 递归合并到 base 上，两边都是字典的子项继续向下合并，其余情况 override 覆盖 base
+func deepMerge(base, override map[string]interface{}) map[string]interface{} {
+	for key, overrideValue := range override {
+		baseMap, baseIsMap := base[key].(map[string]interface{})
+		overrideMap, overrideIsMap := overrideValue.(map[string]interface{})
+		if baseIsMap && overrideIsMap {
+			base[key] = deepMerge(baseMap, overrideMap)
+		} else {
+			base[key] = overrideValue
+		}
+	}
+	return base
+}
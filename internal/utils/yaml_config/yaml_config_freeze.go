@@ -0,0 +1,64 @@
+package yaml_config
+
+import (
+	"apier/internal/global/custom_errors"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// frozenKeys 被冻结的键及其冻结时的值，键名统一为小写，与 viper 保持一致
+type frozenKeys struct {
+	mu     sync.Mutex
+	values map[string]interface{}
+}
+
+func newFrozenKeys() *frozenKeys {
+	return &frozenKeys{values: make(map[string]interface{})}
+}
+
+func (f *frozenKeys) isFrozen(keyName string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, exists := f.values[strings.ToLower(keyName)]
+	return exists
+}
+
+// FreezeKeys 冻结指定的键（例如安全相关配置），此后 Set 与重新载入都无法修改它们，其余键仍可动态变化
+// 冻结值以 viper 覆盖值的形式固定下来，重新载入时若文件中的值发生了变化，保留旧值并记录告警
+func (y *yamlConfig) FreezeKeys(keys ...string) {
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	y.frozen.mu.Lock()
+	defer y.frozen.mu.Unlock()
+	for _, keyName := range keys {
+		value := deepCopyValue(y.viper.Get(keyName))
+		y.frozen.values[strings.ToLower(keyName)] = value
+		y.viper.Set(keyName, value)
+	}
+	y.clearCache()
+}
+
+// checkFrozenKeys 重新载入后对比文件中的值与冻结值，不一致时记录告警
+func (y *yamlConfig) checkFrozenKeys() {
+	y.frozen.mu.Lock()
+	defer y.frozen.mu.Unlock()
+	if len(y.frozen.values) == 0 {
+		return
+	}
+	// 冻结值作为覆盖值优先于文件，需要单独读取一次文件才能拿到文件中的最新值
+	fileConfig := viper.New()
+	fileConfig.SetConfigFile(y.viper.ConfigFileUsed())
+	fileConfig.SetConfigType("yml")
+	if err := fileConfig.ReadInConfig(); err != nil {
+		logError(custom_errors.ErrorsConfigReloadFail, zap.Error(err))
+		return
+	}
+	for keyName, value := range y.frozen.values {
+		if !reflect.DeepEqual(fileConfig.Get(keyName), value) {
+			logWarn(custom_errors.ErrorsConfigKeyFrozen+keyName, zap.Any("kept", value), zap.Any("ignored", fileConfig.Get(keyName)))
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package yaml_config
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// valueHistory 记录指定键在多次重新载入之间的取值变化，每个键最多保留 sizes 中对应数量的值
+type valueHistory struct {
+	mu     sync.Mutex
+	sizes  map[string]int
+	values map[string][]interface{}
+}
+
+func newValueHistory() *valueHistory {
+	return &valueHistory{sizes: make(map[string]int), values: make(map[string][]interface{})}
+}
+
+// EnableHistory 开始记录指定键的取值历史，每个键最多保留最近 size 个不同的值，用于排查灰度发布中反复变化的配置；size 小于 1 时按 1 处理
+// size 只作用于本次传入的键，其它键保留各自的大小；已在记录的键改为新的大小，超出的旧值被丢弃
+func (y *yamlConfig) EnableHistory(size int, keys ...string) {
+	if size < 1 {
+		size = 1
+	}
+	// 先读取初始值再获取 history.mu，保持先 y.mu 后 history.mu 的加锁顺序
+	initial := make(map[string]interface{}, len(keys))
+	for _, keyName := range keys {
+		initial[strings.ToLower(keyName)] = deepCopyValue(y.viperGet(keyName))
+	}
+	y.history.mu.Lock()
+	defer y.history.mu.Unlock()
+	for lowerKey, value := range initial {
+		y.history.sizes[lowerKey] = size
+		values, exists := y.history.values[lowerKey]
+		if !exists {
+			values = []interface{}{value}
+		}
+		y.history.values[lowerKey] = trimHistory(values, size)
+	}
+}
+
+// RecentValues 返回某个键最近记录的 n 个值，按时间从旧到新排列；未开启记录的键或 n 小于 1 时返回 nil
+func (y *yamlConfig) RecentValues(keyName string, n int) []interface{} {
+	if n < 1 {
+		return nil
+	}
+	y.history.mu.Lock()
+	defer y.history.mu.Unlock()
+	values, exists := y.history.values[strings.ToLower(keyName)]
+	if !exists {
+		return nil
+	}
+	if n < len(values) {
+		values = values[len(values)-n:]
+	}
+	return append([]interface{}(nil), values...)
+}
+
+// recordHistory 重新载入后记录发生变化的值，调用方需持有 y.mu
+func (y *yamlConfig) recordHistory() {
+	y.history.mu.Lock()
+	defer y.history.mu.Unlock()
+	for keyName, values := range y.history.values {
+		value := y.viper.Get(keyName)
+		if len(values) > 0 && reflect.DeepEqual(values[len(values)-1], value) {
+			continue
+		}
+		y.history.values[keyName] = trimHistory(append(values, deepCopyValue(value)), y.history.sizes[keyName])
+	}
+}
+
+// trimHistory 只保留最近的 size 个值
+func trimHistory(values []interface{}, size int) []interface{} {
+	if len(values) > size {
+		return values[len(values)-size:]
+	}
+	return values
+}
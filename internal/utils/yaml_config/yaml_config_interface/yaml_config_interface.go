@@ -1,19 +1,177 @@
 package yaml_config_interface
 
 import (
+	"context"
+	"encoding/json"
+	"github.com/spf13/pflag"
+	"regexp"
 	"time"
 )
 
 type YamlConfigInterface interface {
 	ConfigFileChangeListen()
+	PauseWatch()
+	ResumeWatch()
+	ListenSignals(ctx context.Context)
+	Reload() error
+	SetReloadCanary(fn func(candidate YamlConfigInterface) error)
+	MergeConfig(fileNames ...string) error
+	ReloadMergedFile(fileName string) error
+	Set(keyName string, value interface{}) error
+	FreezeKeys(keys ...string)
+	Seal()
+	SetTrimMapStrings(enabled bool)
+	SetStripSliceComments(enabled bool)
+	SetNilCachePolicy(policy NilCachePolicy)
+	RequireIf(keyName, condKey string, condValue interface{}) error
+	CheckRules() error
+	RegisterValueMigration(keyName string, mapping map[string]string)
+	EnableHistory(size int, keys ...string)
+	RecentValues(keyName string, n int) []interface{}
+	BindPFlags(flagSet *pflag.FlagSet) error
+	AutomaticEnv(envPrefix string)
+	UnmatchedEnvVars(prefix string) []string
+	AuditEnvCoverage() []string
+	ExportEnvFile(filePath, prefix string) error
+	ExportEnvFileMasked(filePath, prefix string) error
+	Warmup(keys ...string)
+	CacheSnapshot() map[string]interface{}
+	RestoreCache(snapshot map[string]interface{})
+	PersistCache(path string) error
+	LoadPersistedCache(path string) error
+	CheckVersion(expected int) error
+	SubscribeKey(keyName string) (<-chan interface{}, func())
+	OnChange(fn func(event ReloadEvent)) func()
+	Close()
+	DiffAgainstFile(fileName string) ([]ChangedKey, error)
+	CheckTypeConsistency(files ...string) []error
+	ConfigChecksum() string
+	ConfigModTime() (time.Time, error)
+	MarshalStable() ([]byte, error)
+	LoadInto(root interface{}) error
+	LoadIntoWithSupersede(root interface{}, onSupersede func(old interface{})) error
+	BindAll(specs map[string]interface{}) error
+	SectionTypeReport(keyName string) map[string]string
+	AllSettingsFlattened() map[string]interface{}
+	GetByPattern(pattern string) map[string]interface{}
+	AllSettingsMasked() map[string]interface{}
+	DebugString() string
+	LogEffectiveConfig()
+	EnableDynamicLogLevel(key string) error
+	DeclareTypes(spec map[string]string)
+	EnforceTypes(spec map[string]string) error
+	SlowReads(threshold time.Duration) []string
+	VerifyCacheTypes() []error
 	Clone(fileName string) YamlConfigInterface
+	CloneE(fileName string) (YamlConfigInterface, error)
+	CloneShared(fileName string) YamlConfigInterface
+	View(overrides map[string]interface{}) YamlConfigInterface
 	Get(keyName string) interface{}
 	GetString(keyName string) string
+	GetStringE(keyName string) (string, error)
+	GetStringChecked(keyName string, check func(string) error) (string, error)
+	SetSecretResolver(resolver SecretResolver)
+	SetSecretFileProfile(profile string, baseDirs map[string]string)
+	SetDecryptor(decryptor Decryptor)
+	SetEncryptor(encryptor Encryptor)
+	WriteConfigAs(filePath string) error
 	GetBool(keyName string) bool
 	GetInt(keyName string) int
 	GetInt32(keyName string) int32
 	GetInt64(keyName string) int64
 	GetFloat64(keyName string) float64
 	GetDuration(keyName string) time.Duration
+	GetStrictDuration(keyName string) (time.Duration, error)
 	GetStringSlice(keyName string) []string
+	GetNonEmptyStringSlice(keyName string) ([]string, error)
+	GetStringSet(keyName string) map[string]struct{}
+	GetLines(keyName string) []string
+	GetGlobSlice(keyName string) ([]string, error)
+	GetPath(keyName string) string
+	GetStringMap(keyName string) map[string]interface{}
+	GetStringMapE(keyName string) (map[string]interface{}, error)
+	GetStringMapWithDefaults(keyName string, defaults map[string]interface{}) map[string]interface{}
+	GetSectionWithSchema(keyName string, schema Schema) (map[string]interface{}, error)
+	GetSection(keyName string) Section
+	RegisterSectionAlias(oldSection, newSection string)
+	GetStringMapString(keyName string) map[string]string
+	GetStringMapStringStrict(keyName string) (map[string]string, error)
+	GetStringMapStringResolved(keyName string) (map[string]string, error)
+	RegisterMapProvider(keyName string, provider func() map[string]string)
+	GetStringMapStringProvided(keyName string) map[string]string
+	GetStringMapStringEnvFallback(keyName string, expectedKeys []string, envPrefix string) map[string]string
+	GetStringMapStringWithEnv(keyName string, envVars []string, envWins bool) map[string]string
+	GetStringMapStringValidated(keyName string, pattern *regexp.Regexp) (map[string]string, error)
+	GetFlatStringMap(keyName, sep string) map[string]string
+	GetOrderedMapKeys(keyName string) []string
+	GetMapKeys(keyName string) []string
+	GetMapValues(keyName string) []interface{}
+	MergeMaps(keys ...string) map[string]string
+	GetCIMap(keyName string) func(k string) (string, bool)
+	GetRawSections(keyName string) (map[string]json.RawMessage, error)
+	BuildInverted(keyName string) map[string][]string
+	InvertMapSlice(keyName string) map[string][]string
+}
+
+// Section 配置段读取器，键名相对于配置段
+type Section interface {
+	Get(keyName string) interface{}
+	GetString(keyName string) string
+	GetInt(keyName string) int
+	GetBool(keyName string) bool
+}
+
+// SecretResolver 密钥解析器，用于把 keyring:service/account 形式的配置值解析为操作系统钥匙串等密钥存储中的真实值
+type SecretResolver interface {
+	ResolveSecret(service, account string) (string, error)
+}
+
+// Decryptor 解密 enc: 开头的加密配置值，参数为去掉前缀后的密文
+type Decryptor interface {
+	Decrypt(ciphertext string) (string, error)
+}
+
+// Encryptor 与 Decryptor 对应，WriteConfigAs 写出配置时用于加密敏感项，返回值不含 enc: 前缀
+type Encryptor interface {
+	Encrypt(plaintext string) (string, error)
+}
+
+// SchemaField 配置段中单个子键的类型与默认值，Type 的可选值与 LoadOptions.Types 相同
+type SchemaField struct {
+	Type    string
+	Default interface{} // 配置文件未设置该子键时使用，为 nil 时结果中不包含该子键
+}
+
+// Schema 配置段的结构描述，子键 => SchemaField，供 GetSectionWithSchema 使用
+type Schema map[string]SchemaField
+
+// NilCachePolicy 读取不存在的键（viper 返回 nil）时是否缓存读取结果
+type NilCachePolicy int32
+
+const (
+	NilCacheSkip  NilCachePolicy = iota // 默认：不缓存，之后通过环境变量等方式出现的值可以立即读到，代价是每次读取不存在的键都会访问 viper
+	NilCacheStore                       // 缓存零值，重复读取不存在的键不再访问 viper，但之后出现的值在重新载入前会被缓存的零值掩盖
+)
+
+// ReloadEvent 一次重新载入的结果，传给 OnChange 登记的回调
+type ReloadEvent struct {
+	OldChecksum string       // 重新载入前读取的配置文件内容的 sha256，与 ConfigChecksum 不同，不包含环境变量等覆盖
+	NewChecksum string       // 重新载入后读取的配置文件内容的 sha256
+	Changes     []ChangedKey // 发生变化的键，按键名排序
+	Time        time.Time    // 重新载入完成的时间
+}
+
+// 配置项变化类型
+const (
+	KeyAdded   = "added"   // 新增的键
+	KeyRemoved = "removed" // 删除的键
+	KeyChanged = "changed" // 值发生变化的键
+)
+
+// ChangedKey 两份配置之间某个键的差异
+type ChangedKey struct {
+	Key      string
+	Type     string // KeyAdded、KeyRemoved、KeyChanged 之一
+	OldValue interface{}
+	NewValue interface{}
 }
@@ -0,0 +1,12 @@
+//go:build proto
+
+package yaml_config_interface
+
+import "google.golang.org/protobuf/proto"
+
+// ProtoUnmarshaler 以 -tags proto 编译时配置实例实现的接口，用法：
+//
+//	err := variable.ConfigYaml.(yaml_config_interface.ProtoUnmarshaler).UnmarshalProto("Service", &serviceConfig)
+type ProtoUnmarshaler interface {
+	UnmarshalProto(keyName string, msg proto.Message) error
+}
@@ -0,0 +1,49 @@
+package yaml_config
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// readLatencies 每个键的最大读取耗时，键名统一为小写
+// 只在可能较慢的环节记录：缓存未命中时读取 viper、解析密钥引用与加密值、调用字典提供函数，命中缓存的读取不计时
+type readLatencies struct {
+	mu  sync.Mutex
+	max map[string]time.Duration
+}
+
+func newReadLatencies() *readLatencies {
+	return &readLatencies{max: make(map[string]time.Duration)}
+}
+
+// record 记录一次读取耗时，只保留最大值
+func (r *readLatencies) record(keyName string, elapsed time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	lowerKey := strings.ToLower(keyName)
+	if elapsed > r.max[lowerKey] {
+		r.max[lowerKey] = elapsed
+	}
+}
+
+// recordLatency 记录从 start 开始到现在的读取耗时，供 defer 使用
+func (y *yamlConfig) recordLatency(keyName string, start time.Time) {
+	y.latencies.record(keyName, time.Since(start))
+}
+
+// SlowReads 列出读取耗时曾经超过 threshold 的键（小写，按键名排序），用于发现远程密钥存储之类较慢的配置来源
+// 本地文件的读取耗时接近于零，通常只有密钥解析器、字典提供函数等外部来源会出现在结果中
+func (y *yamlConfig) SlowReads(threshold time.Duration) []string {
+	y.latencies.mu.Lock()
+	defer y.latencies.mu.Unlock()
+	keys := make([]string, 0)
+	for keyName, elapsed := range y.latencies.max {
+		if elapsed > threshold {
+			keys = append(keys, keyName)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
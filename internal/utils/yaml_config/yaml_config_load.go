@@ -0,0 +1,90 @@
+package yaml_config
+
+import (
+	"apier/internal/global/custom_errors"
+	"apier/internal/utils/yaml_config/yaml_config_interface"
+	"errors"
+	"fmt"
+	"github.com/spf13/cast"
+	"sort"
+)
+
+// LoadOptions LoadWithReport 的加载参数
+type LoadOptions struct {
+	FileName     string            // 需要读取的文件名，默认为：config
+	RequiredKeys []string          // 必须存在的键
+	Types        map[string]string // 键 => 期望类型，可选值：string、int、int32、int64、float64、bool、duration、[]string、map
+}
+
+// LoadWithReport 加载配置文件，但不在首个错误处终止，而是尽可能收集解析错误、缺失的必填键、类型不匹配等全部问题
+// 即使存在错误也会返回已加载的（可能不完整的）配置实例，适合配置检查类的工具使用
+func LoadWithReport(opts LoadOptions) (yaml_config_interface.YamlConfigInterface, []error) {
+	if opts.FileName == "" {
+		opts.FileName = "config"
+	}
+	ymlConfig := newYamlConfig(opts.FileName)
+
+	var errs []error
+	if err := ymlConfig.viper.ReadInConfig(); err != nil {
+		errs = append(errs, errors.New(custom_errors.ErrorsConfigInitFail+err.Error()))
+	}
+	// 读取失败时返回的是空配置，仍然允许读取（得到零值），因此同样视为已载入
+	ymlConfig.markLoaded()
+	for _, keyName := range opts.RequiredKeys {
+		if !ymlConfig.viper.IsSet(keyName) {
+			errs = append(errs, errors.New(custom_errors.ErrorsConfigKeyNotExists+keyName))
+		}
+	}
+
+	// 按键名排序，保证错误顺序稳定
+	typedKeys := make([]string, 0, len(opts.Types))
+	for keyName := range opts.Types {
+		typedKeys = append(typedKeys, keyName)
+	}
+	sort.Strings(typedKeys)
+	for _, keyName := range typedKeys {
+		if !ymlConfig.viper.IsSet(keyName) {
+			continue
+		}
+		if err := checkValueType(keyName, ymlConfig.viper.Get(keyName), opts.Types[keyName]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return ymlConfig, errs
+}
+
+// checkValueType 校验配置值能否转换为声明的类型
+func checkValueType(keyName string, value interface{}, typeName string) error {
+	_, err := convertValue(keyName, value, typeName)
+	return err
+}
+
+// convertValue 将配置值转换为声明的类型，typeName 的可选值见 LoadOptions.Types
+func convertValue(keyName string, value interface{}, typeName string) (converted interface{}, err error) {
+	switch typeName {
+	case "string":
+		converted, err = cast.ToStringE(value)
+	case "int":
+		converted, err = cast.ToIntE(value)
+	case "int32":
+		converted, err = cast.ToInt32E(value)
+	case "int64":
+		converted, err = cast.ToInt64E(value)
+	case "float64":
+		converted, err = cast.ToFloat64E(value)
+	case "bool":
+		converted, err = cast.ToBoolE(value)
+	case "duration":
+		converted, err = cast.ToDurationE(value)
+	case "[]string":
+		converted, err = cast.ToStringSliceE(value)
+	case "map":
+		converted, err = cast.ToStringMapE(value)
+	default:
+		return nil, errors.New(custom_errors.ErrorsConfigTypeNotSupport + typeName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf(custom_errors.ErrorsConfigValueTypeMismatch, keyName, typeName, err.Error())
+	}
+	return converted, nil
+}
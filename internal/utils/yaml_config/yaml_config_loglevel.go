@@ -0,0 +1,59 @@
+package yaml_config
+
+import (
+	"apier/internal/global/custom_errors"
+	"apier/internal/global/variable"
+	"errors"
+	"fmt"
+	"github.com/spf13/cast"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"sync"
+)
+
+// logLevelBinding EnableDynamicLogLevel 登记的日志级别配置键，为空表示未开启
+type logLevelBinding struct {
+	mu  sync.Mutex
+	key string
+}
+
+// EnableDynamicLogLevel 以 key 对应的配置项（debug、info、warn、error 等）设置全局日志级别 variable.ZapLogLevel，之后每次重新载入都重新应用
+// variable.ZapLog 由 zap_factory 基于该级别创建，因此修改配置文件即可调整日志级别，无需重启
+// 配置项不存在或取值无效时返回错误且不修改级别；重新载入时取值无效则保留原级别并记录错误日志
+func (y *yamlConfig) EnableDynamicLogLevel(key string) error {
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	if err := y.applyLogLevel(key); err != nil {
+		return err
+	}
+	y.logLevel.mu.Lock()
+	y.logLevel.key = key
+	y.logLevel.mu.Unlock()
+	return nil
+}
+
+// reapplyLogLevel 重新载入后重新应用日志级别，调用方需持有 y.mu
+func (y *yamlConfig) reapplyLogLevel() {
+	y.logLevel.mu.Lock()
+	key := y.logLevel.key
+	y.logLevel.mu.Unlock()
+	if key == "" {
+		return
+	}
+	if err := y.applyLogLevel(key); err != nil {
+		logError(custom_errors.ErrorsConfigReloadFail, zap.Error(err))
+	}
+}
+
+// applyLogLevel 读取并应用日志级别，调用方需持有 y.mu
+func (y *yamlConfig) applyLogLevel(key string) error {
+	if !y.viper.IsSet(key) {
+		return errors.New(custom_errors.ErrorsConfigKeyNotExists + key)
+	}
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(cast.ToString(y.viper.Get(key)))); err != nil {
+		return fmt.Errorf(custom_errors.ErrorsConfigLogLevelInvalid+"%s：%w", key, err)
+	}
+	variable.ZapLogLevel.SetLevel(level)
+	return nil
+}
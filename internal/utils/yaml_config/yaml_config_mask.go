@@ -0,0 +1,73 @@
+package yaml_config
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"sort"
+	"strings"
+)
+
+// 脱敏后的占位值
+const maskedValue = "******"
+
+// 键名（不区分大小写）包含以下任一词语时视为敏感项，导出、打印时脱敏
+var sensitiveKeyWords = []string{"password", "passwd", "pwd", "secret", "token", "auth", "credential", "private"}
+
+// isSensitiveKey 判断键名是否为敏感项
+func isSensitiveKey(keyName string) bool {
+	lowerKey := strings.ToLower(keyName)
+	for _, word := range sensitiveKeyWords {
+		if strings.Contains(lowerKey, word) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskSettings 递归拷贝配置并替换敏感项的值（敏感项下的整个子配置段一并替换），不修改原字典
+func maskSettings(settings map[string]interface{}) map[string]interface{} {
+	masked := make(map[string]interface{}, len(settings))
+	for key, value := range settings {
+		if isSensitiveKey(key) {
+			masked[key] = maskedValue
+		} else if subSettings, ok := value.(map[string]interface{}); ok {
+			masked[key] = maskSettings(subSettings)
+		} else {
+			masked[key] = deepCopyValue(value)
+		}
+	}
+	return masked
+}
+
+// AllSettingsMasked 返回全部配置，敏感项已脱敏，仅用于日志、导出，业务代码读取真实值请使用各个 getter
+func (y *yamlConfig) AllSettingsMasked() map[string]interface{} {
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	return maskSettings(y.viper.AllSettings())
+}
+
+// DebugString 以 `键 = 值` 的形式逐行输出全部配置，按键名排序，敏感项已脱敏
+func (y *yamlConfig) DebugString() string {
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	keys := y.viper.AllKeys()
+	sort.Strings(keys)
+	var builder strings.Builder
+	for _, keyName := range keys {
+		value := y.viper.Get(keyName)
+		for _, segment := range strings.Split(keyName, ".") {
+			if isSensitiveKey(segment) {
+				value = maskedValue
+				break
+			}
+		}
+		builder.WriteString(fmt.Sprintf("%s = %v\n", keyName, value))
+	}
+	return builder.String()
+}
+
+// LogEffectiveConfig 以 Info 级别输出一次最终生效的全部配置（已合并文件、命令行参数与环境变量覆盖），格式与 DebugString 相同，敏感项已脱敏
+// 需要显式调用，建议在启动阶段配置初始化完成后调用一次，避免日志膨胀
+func (y *yamlConfig) LogEffectiveConfig() {
+	logInfo("当前生效的配置", zap.String("config", y.DebugString()))
+}
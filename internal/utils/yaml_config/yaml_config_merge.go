@@ -0,0 +1,172 @@
+package yaml_config
+
+import (
+	"apier/internal/global/custom_errors"
+	"apier/internal/global/variable"
+	"errors"
+	"path/filepath"
+	"strings"
+)
+
+// mergeTracking 记录主配置（含内嵌默认配置、运行时覆盖值）与每个合并文件分别提供的叶子键，供 ReloadMergedFile 判断一个文件的变化影响哪些键
+// base 为 nil 表示尚未合并过文件；全部字段由 y.mu 保护
+type mergeTracking struct {
+	base  map[string]struct{}
+	files map[string]map[string]struct{}
+}
+
+func newMergeTracking() *mergeTracking {
+	return &mergeTracking{files: make(map[string]map[string]struct{})}
+}
+
+// MergeConfig 将一个或多个文件依次合并到当前配置之上，文件类型按扩展名识别（yml、yaml、json、toml 等 viper 支持的格式）
+// 优先级：后合并的文件 > 先合并的文件 > 主配置文件，例如在 yml 基础配置上叠加一层 json 覆盖
+// 相对路径相对于 configs 目录，没有扩展名时按 yml 处理；合并的文件会记录下来，重新载入主配置文件后按原顺序重新合并
+func (y *yamlConfig) MergeConfig(fileNames ...string) error {
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	if y.merges.base == nil {
+		y.merges.base = settingKeys(flattenSettings(y.viper))
+	}
+	for _, fileName := range fileNames {
+		if err := y.mergeFile(fileName); err != nil {
+			return err
+		}
+		y.mergedFiles = append(y.mergedFiles, fileName)
+	}
+	y.clearCache()
+	return nil
+}
+
+// applyMergedFiles 主配置文件重新读取后，按原顺序重新合并此前合并过的文件，调用方需持有 y.mu
+func (y *yamlConfig) applyMergedFiles() error {
+	if len(y.mergedFiles) == 0 {
+		return nil
+	}
+	y.merges.base = settingKeys(flattenSettings(y.viper))
+	y.merges.files = make(map[string]map[string]struct{})
+	for _, fileName := range y.mergedFiles {
+		if err := y.mergeFile(fileName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mergeFile 按文件扩展名单独读取文件后合并到当前配置，并记录该文件提供的键，调用方需持有 y.mu
+func (y *yamlConfig) mergeFile(fileName string) error {
+	fileConfig, err := readConfigFile(fileName)
+	if err != nil {
+		return err
+	}
+	if err := y.viper.MergeConfigMap(fileConfig.AllSettings()); err != nil {
+		return err
+	}
+	y.merges.files[fileName] = settingKeys(flattenSettings(fileConfig))
+	return nil
+}
+
+// ReloadMergedFile 只重新合并 MergeConfig 合并过的某一个文件，例如只有 db.yml 发生变化时使用
+// 只失效与该文件的键相关的缓存，其它配置段的缓存继续有效；冻结键、封存、规则校验、订阅通知等与 Reload 相同
+// 以下情况无法确定影响范围，退回到完整的 Reload：该文件删除了键、该文件的键同时由主配置或其它合并文件提供、注册了配置段别名、设置了 SetReloadCanary 或 EnforceTypes
+func (y *yamlConfig) ReloadMergedFile(fileName string) error {
+	if y.viper == nil {
+		return errors.New(custom_errors.ErrorsConfigNotLoaded)
+	}
+	y.mu.Lock()
+	capture := y.beginChange()
+	if err := y.reloadMergedFile(fileName); err != nil {
+		y.mu.Unlock()
+		return err
+	}
+	event := y.endChange(capture)
+	y.mu.Unlock()
+	y.dispatchChange(event)
+	y.dispatchSuperseded()
+	return nil
+}
+
+// reloadMergedFile ReloadMergedFile 的实现，调用方需持有 y.mu
+func (y *yamlConfig) reloadMergedFile(fileName string) error {
+	oldKeys, merged := y.merges.files[fileName]
+	if !merged {
+		return errors.New(custom_errors.ErrorsConfigFileNotMerged + fileName)
+	}
+	if y.canary.get() != nil || y.hasEnforcedTypes() || y.hasAliases() {
+		return y.reload()
+	}
+	fileConfig, err := readConfigFile(fileName)
+	if err != nil {
+		return err
+	}
+	newKeys := settingKeys(flattenSettings(fileConfig))
+	if y.mergeAmbiguous(fileName, oldKeys, newKeys) {
+		return y.reload()
+	}
+	if err := y.viper.MergeConfigMap(fileConfig.AllSettings()); err != nil {
+		return err
+	}
+	y.merges.files[fileName] = newKeys
+	y.invalidateCache(newKeys)
+	y.afterConfigChange()
+	return nil
+}
+
+// mergeAmbiguous 判断单独重新合并一个文件是否可能得到与完整重新载入不同的结果，调用方需持有 y.mu
+func (y *yamlConfig) mergeAmbiguous(fileName string, oldKeys, newKeys map[string]struct{}) bool {
+	for keyName := range oldKeys {
+		if _, exists := newKeys[keyName]; !exists {
+			return true
+		}
+	}
+	for keyName := range newKeys {
+		if keyRelated(keyName, y.merges.base) {
+			return true
+		}
+		for otherFile, otherKeys := range y.merges.files {
+			if otherFile != fileName && keyRelated(keyName, otherKeys) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// invalidateCache 只删除与 keys 相关（相同、上级或下级键）的缓存，以及带 # 的组合缓存（默认值合并、引用解析等结果可能依赖任意键）
+// 同时增加缓存代数，使读取中的 getter 放弃写入旧值，调用方需持有 y.mu
+func (y *yamlConfig) invalidateCache(keys map[string]struct{}) {
+	y.cacheGen.Add(1)
+	for _, cacheKey := range containerFactory.Keys(y.cachePrefix) {
+		keyName := strings.ToLower(strings.TrimPrefix(cacheKey, y.cachePrefix))
+		if strings.ContainsAny(keyName, "#*?") || keyRelated(keyName, keys) {
+			containerFactory.Delete(cacheKey)
+		}
+	}
+}
+
+// keyRelated 判断 keyName 是否与 keys 中的某个键相同，或是其上级、下级键
+func keyRelated(keyName string, keys map[string]struct{}) bool {
+	for other := range keys {
+		if keyName == other || strings.HasPrefix(keyName, other+".") || strings.HasPrefix(other, keyName+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// settingKeys 展开后配置的键集合
+func settingKeys(settings map[string]interface{}) map[string]struct{} {
+	keys := make(map[string]struct{}, len(settings))
+	for keyName := range settings {
+		keys[keyName] = struct{}{}
+	}
+	return keys
+}
+
+// resolveConfigPath 相对路径按 configs 目录解析，绝对路径原样返回
+func resolveConfigPath(fileName string) string {
+	if filepath.IsAbs(fileName) {
+		return fileName
+	}
+	return filepath.Join(variable.BasePath, "configs", fileName)
+}
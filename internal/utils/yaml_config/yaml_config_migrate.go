@@ -0,0 +1,51 @@
+package yaml_config
+
+import (
+	"apier/internal/global/custom_errors"
+	"go.uber.org/zap"
+	"strings"
+	"sync"
+)
+
+// valueMigrations 键 => (废弃取值 => 新取值)，键名统一为小写；warned 记录已经告警过的 键=废弃取值，每个组合只告警一次
+type valueMigrations struct {
+	mu       sync.Mutex
+	mappings map[string]map[string]string
+	warned   map[string]struct{}
+}
+
+func newValueMigrations() *valueMigrations {
+	return &valueMigrations{mappings: make(map[string]map[string]string), warned: make(map[string]struct{})}
+}
+
+// RegisterValueMigration 登记某个键的取值迁移，例如日志级别 {"warning": "warn"}，此后 GetString 读到废弃取值时返回新值
+// 每个废弃取值第一次被读取时记录一条告警，提醒修改配置文件；重复登记同一个键时覆盖之前的映射
+func (y *yamlConfig) RegisterValueMigration(keyName string, mapping map[string]string) {
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	y.migrations.mu.Lock()
+	defer y.migrations.mu.Unlock()
+	dst := make(map[string]string, len(mapping))
+	for oldValue, newValue := range mapping {
+		dst[oldValue] = newValue
+	}
+	y.migrations.mappings[strings.ToLower(keyName)] = dst
+	// 已缓存的可能是迁移前的取值
+	y.clearCache()
+}
+
+// migrateValue 返回迁移后的取值，没有登记迁移或取值不是废弃值时原样返回
+func (y *yamlConfig) migrateValue(keyName, value string) string {
+	y.migrations.mu.Lock()
+	defer y.migrations.mu.Unlock()
+	lowerKey := strings.ToLower(keyName)
+	newValue, exists := y.migrations.mappings[lowerKey][value]
+	if !exists {
+		return value
+	}
+	if _, warned := y.migrations.warned[lowerKey+"="+value]; !warned {
+		y.migrations.warned[lowerKey+"="+value] = struct{}{}
+		logWarn(custom_errors.ErrorsConfigValueMigrated+keyName, zap.String("deprecated", value), zap.String("migrated", newValue))
+	}
+	return newValue
+}
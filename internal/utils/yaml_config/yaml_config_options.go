@@ -0,0 +1,73 @@
+package yaml_config
+
+import (
+	"apier/internal/utils/yaml_config/yaml_config_interface"
+	"strings"
+	"sync/atomic"
+)
+
+// instanceOptions 配置实例上可在运行时切换的读取选项
+type instanceOptions struct {
+	trimMapStrings    atomic.Bool  // GetStringMapString 是否去掉键、值两端的空白
+	stripSliceComment atomic.Bool  // GetStringSlice 是否去掉元素末尾的 # 注释
+	nilCachePolicy    atomic.Int32 // 不存在的键是否缓存，取值为 yaml_config_interface.NilCachePolicy
+}
+
+// clone 拷贝一份当前选项，供 Clone 出的实例使用
+func (o *instanceOptions) clone() *instanceOptions {
+	dst := new(instanceOptions)
+	dst.trimMapStrings.Store(o.trimMapStrings.Load())
+	dst.stripSliceComment.Store(o.stripSliceComment.Load())
+	dst.nilCachePolicy.Store(o.nilCachePolicy.Load())
+	return dst
+}
+
+// SetTrimMapStrings 开启后 GetStringMapString 返回并缓存去掉键、值两端空白后的字典，避免 "host " 之类的键查找不到
+func (y *yamlConfig) SetTrimMapStrings(enabled bool) {
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	y.opts.trimMapStrings.Store(enabled)
+	y.clearCache()
+}
+
+// SetStripSliceComments 开启后 GetStringSlice 去掉每个元素末尾的 # 注释并去掉两端空白，例如 "10.0.0.1 # 机房A" 读取为 "10.0.0.1"
+// 只有位于开头或前面是空白的 # 才视为注释，因此 http://host/#/path 这类值不受影响；去掉注释后为空的元素被丢弃
+func (y *yamlConfig) SetStripSliceComments(enabled bool) {
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	y.opts.stripSliceComment.Store(enabled)
+	y.clearCache()
+}
+
+// normalizeStringSlice 按实例选项规范化字符串列表
+func (y *yamlConfig) normalizeStringSlice(value []string) []string {
+	if !y.opts.stripSliceComment.Load() {
+		return value
+	}
+	stripped := make([]string, 0, len(value))
+	for _, item := range value {
+		if item = strings.TrimSpace(stripInlineComment(item)); item != "" {
+			stripped = append(stripped, item)
+		}
+	}
+	return stripped
+}
+
+// stripInlineComment 去掉从开头或空白之后的第一个 # 开始的内容
+func stripInlineComment(value string) string {
+	for i, r := range value {
+		if r == '#' && (i == 0 || value[i-1] == ' ' || value[i-1] == '\t') {
+			return value[:i]
+		}
+	}
+	return value
+}
+
+// SetNilCachePolicy 设置读取不存在的键时是否缓存结果，默认 NilCacheSkip
+// 缓存不存在的键会掩盖之后出现的值：例如开启 AutomaticEnv 后先读取了未设置的键，再设置对应的环境变量，缓存的零值在重新载入前一直生效
+func (y *yamlConfig) SetNilCachePolicy(policy yaml_config_interface.NilCachePolicy) {
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	y.opts.nilCachePolicy.Store(int32(policy))
+	y.clearCache()
+}
@@ -0,0 +1,80 @@
+package yaml_config
+
+import (
+	"container/list"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// 每个实例最多缓存的已编译键名模式数量，超出时淘汰最久未使用的模式，避免来自请求参数的模式使内存无限增长
+const maxKeyPatterns = 128
+
+// keyPatternCache 已编译键名模式的 LRU 缓存，键为小写的模式；模式与配置内容无关，重新载入时无需清空
+type keyPatternCache struct {
+	mu    sync.Mutex
+	order *list.List // 元素为 *keyPatternEntry，越靠前越近使用
+	items map[string]*list.Element
+}
+
+type keyPatternEntry struct {
+	pattern string
+	matcher *regexp.Regexp
+}
+
+func newKeyPatternCache() *keyPatternCache {
+	return &keyPatternCache{order: list.New(), items: make(map[string]*list.Element)}
+}
+
+// get 返回已编译的模式，未缓存时编译并写入缓存
+func (c *keyPatternCache) get(pattern string) *regexp.Regexp {
+	pattern = strings.ToLower(pattern)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, exists := c.items[pattern]; exists {
+		c.order.MoveToFront(elem)
+		return elem.Value.(*keyPatternEntry).matcher
+	}
+	matcher := compileKeyPattern(pattern)
+	c.items[pattern] = c.order.PushFront(&keyPatternEntry{pattern: pattern, matcher: matcher})
+	if c.order.Len() > maxKeyPatterns {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*keyPatternEntry).pattern)
+	}
+	return matcher
+}
+
+// GetByPattern 返回完整键名匹配模式的全部叶子配置项，例如 plugins.*.enabled 返回每个插件的 enabled 配置
+// 模式按点号分段：* 匹配一段内的任意字符，? 匹配一段内的单个字符，** 可以跨越多段；匹配不区分大小写
+func (y *yamlConfig) GetByPattern(pattern string) map[string]interface{} {
+	matcher := y.patterns.get(pattern)
+	matched := make(map[string]interface{})
+	for keyName, value := range y.AllSettingsFlattened() {
+		if matcher.MatchString(keyName) {
+			matched[keyName] = value
+		}
+	}
+	return matched
+}
+
+// compileKeyPattern 将小写的键名模式编译为正则表达式
+func compileKeyPattern(pattern string) *regexp.Regexp {
+	var builder strings.Builder
+	builder.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch {
+		case strings.HasPrefix(pattern[i:], "**"):
+			builder.WriteString(".*")
+			i++
+		case pattern[i] == '*':
+			builder.WriteString(`[^.]*`)
+		case pattern[i] == '?':
+			builder.WriteString(`[^.]`)
+		default:
+			builder.WriteString(regexp.QuoteMeta(pattern[i : i+1]))
+		}
+	}
+	builder.WriteString("$")
+	return regexp.MustCompile(builder.String())
+}
@@ -0,0 +1,87 @@
+package yaml_config
+
+import (
+	"apier/internal/global/custom_errors"
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"time"
+)
+
+// 可以持久化的缓存值类型，json 编解码后类型与取值保持不变；含 interface{} 的字典、切片解码后数值类型会变化，不持久化
+var persistableTypes = map[string]reflect.Type{}
+
+func init() {
+	for _, value := range []interface{}{
+		"", false, 0, int32(0), int64(0), float64(0), time.Duration(0),
+		[]string(nil), map[string]string(nil), map[string]struct{}(nil), map[string][]string(nil),
+	} {
+		persistableTypes[reflect.TypeOf(value).String()] = reflect.TypeOf(value)
+	}
+}
+
+// persistedCache PersistCache 写出的文件内容，checksum 为写出时的 ConfigChecksum
+type persistedCache struct {
+	Checksum string                    `json:"checksum"`
+	Entries  map[string]persistedEntry `json:"entries"`
+}
+
+type persistedEntry struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// PersistCache 将当前实例已缓存的配置项写入文件，平滑重启后通过 LoadPersistedCache 恢复，跳过冷启动时的读取
+// 只写出标量、字符串切片与字符串字典，敏感项（见 isSensitiveKey）不会写出；文件权限为 0600
+func (y *yamlConfig) PersistCache(path string) error {
+	persisted := persistedCache{Checksum: y.ConfigChecksum(), Entries: make(map[string]persistedEntry)}
+	for keyName, value := range y.CacheSnapshot() {
+		if value == nil || isSensitiveKey(keyName) {
+			continue
+		}
+		if _, ok := persistableTypes[reflect.TypeOf(value).String()]; !ok {
+			continue
+		}
+		content, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		persisted.Entries[keyName] = persistedEntry{Type: reflect.TypeOf(value).String(), Value: content}
+	}
+	content, err := json.Marshal(persisted)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0600)
+}
+
+// LoadPersistedCache 读取 PersistCache 写出的文件并替换当前实例的缓存
+// 写出之后配置发生过变化（ConfigChecksum 不一致）时返回错误且不修改缓存，调用方按正常的冷启动读取即可
+func (y *yamlConfig) LoadPersistedCache(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var persisted persistedCache
+	if err := json.Unmarshal(content, &persisted); err != nil {
+		return err
+	}
+	if persisted.Checksum != y.ConfigChecksum() {
+		return errors.New(custom_errors.ErrorsConfigCacheStale + path)
+	}
+	snapshot := make(map[string]interface{}, len(persisted.Entries))
+	for keyName, entry := range persisted.Entries {
+		valueType, ok := persistableTypes[entry.Type]
+		if !ok {
+			continue
+		}
+		value := reflect.New(valueType)
+		if err := json.Unmarshal(entry.Value, value.Interface()); err != nil {
+			continue
+		}
+		snapshot[keyName] = value.Elem().Interface()
+	}
+	y.RestoreCache(snapshot)
+	return nil
+}
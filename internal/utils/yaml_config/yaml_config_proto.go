@@ -0,0 +1,69 @@
+//go:build proto
+
+package yaml_config
+
+import (
+	"apier/internal/global/custom_errors"
+	"encoding/json"
+	"fmt"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"strings"
+)
+
+// UnmarshalProto 将配置段转换为 json 后通过 protojson 填充 protobuf 消息，适合以 proto 定义配置结构的服务
+// viper 读取后的键名都是小写，这里按消息描述不区分大小写地还原字段名，因此配置文件中写 source_context、sourceContext、SourceContext 都可以
+// 需要以 -tags proto 编译，避免未使用 protobuf 的程序引入该依赖；通过 yaml_config_interface.ProtoUnmarshaler 断言后调用
+func (y *yamlConfig) UnmarshalProto(keyName string, msg proto.Message) error {
+	section, err := y.GetStringMapE(keyName)
+	if err != nil {
+		return err
+	}
+	content, err := json.Marshal(restoreProtoFieldNames(section, msg.ProtoReflect().Descriptor()))
+	if err != nil {
+		return fmt.Errorf(custom_errors.ErrorsConfigProtoUnmarshal, keyName, err)
+	}
+	if err := protojson.Unmarshal(content, msg); err != nil {
+		return fmt.Errorf(custom_errors.ErrorsConfigProtoUnmarshal, keyName, err)
+	}
+	return nil
+}
+
+// restoreProtoFieldNames 按消息描述把小写的键名替换为 proto 字段名，并递归处理嵌套消息与消息列表，无法对应字段的键原样保留，由 protojson 报错
+func restoreProtoFieldNames(value map[string]interface{}, descriptor protoreflect.MessageDescriptor) map[string]interface{} {
+	fields := make(map[string]protoreflect.FieldDescriptor)
+	for i := 0; i < descriptor.Fields().Len(); i++ {
+		field := descriptor.Fields().Get(i)
+		fields[strings.ToLower(string(field.Name()))] = field
+		fields[strings.ToLower(field.JSONName())] = field
+	}
+	restored := make(map[string]interface{}, len(value))
+	for key, val := range value {
+		field, exists := fields[strings.ToLower(key)]
+		if !exists {
+			restored[key] = val
+			continue
+		}
+		if field.Message() != nil && !field.IsMap() {
+			val = restoreProtoValue(val, field.Message())
+		}
+		restored[string(field.Name())] = val
+	}
+	return restored
+}
+
+// restoreProtoValue 处理嵌套消息字段的值，可能是单个消息（字典）或消息列表
+func restoreProtoValue(value interface{}, descriptor protoreflect.MessageDescriptor) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		return restoreProtoFieldNames(typed, descriptor)
+	case []interface{}:
+		items := make([]interface{}, len(typed))
+		for i, item := range typed {
+			items[i] = restoreProtoValue(item, descriptor)
+		}
+		return items
+	}
+	return value
+}
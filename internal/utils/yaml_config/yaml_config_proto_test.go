@@ -0,0 +1,34 @@
+//go:build proto
+
+package yaml_config
+
+import (
+	"apier/internal/utils/yaml_config/yaml_config_interface"
+	"google.golang.org/protobuf/types/known/apipb"
+	"google.golang.org/protobuf/types/known/typepb"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshalProto(t *testing.T) {
+	y := newTestConfig(t, "config", "Service:\n  Name: apier.v1.UserService\n  Version: v1\n  Syntax: SYNTAX_PROTO3\n  source_context:\n    FileName: user.proto\n  Methods:\n    - Name: GetUser\n      responseStreaming: true\nBad:\n  Name: apier\n  Owner: nobody\n")
+
+	var _ yaml_config_interface.ProtoUnmarshaler = y
+	var api apipb.Api
+	if err := y.UnmarshalProto("Service", &api); err != nil {
+		t.Fatal(err)
+	}
+	if api.GetName() != "apier.v1.UserService" || api.GetVersion() != "v1" || api.GetSyntax() != typepb.Syntax_SYNTAX_PROTO3 {
+		t.Fatalf("unexpected scalar fields: %v", &api)
+	}
+	if api.GetSourceContext().GetFileName() != "user.proto" {
+		t.Fatalf("nested message not populated: %v", &api)
+	}
+	if len(api.GetMethods()) != 1 || api.GetMethods()[0].GetName() != "GetUser" || !api.GetMethods()[0].GetResponseStreaming() {
+		t.Fatalf("repeated nested messages not populated: %v", api.GetMethods())
+	}
+
+	if err := y.UnmarshalProto("Bad", &apipb.Api{}); err == nil || !strings.Contains(err.Error(), "Bad") {
+		t.Fatalf("expected error naming the section for unknown field, got %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+package yaml_config
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// mapProviders 键 => 由代码提供字典项的函数，键名统一为小写
+type mapProviders struct {
+	mu        sync.Mutex
+	providers map[string]func() map[string]string
+}
+
+func newMapProviders() *mapProviders {
+	return &mapProviders{providers: make(map[string]func() map[string]string)}
+}
+
+// RegisterMapProvider 为字符串字典配置项登记一个提供函数，GetStringMapStringProvided 读取时与配置文件中的字典合并
+// 重复登记同一个键时覆盖之前的函数，provider 为 nil 表示取消登记
+func (y *yamlConfig) RegisterMapProvider(keyName string, provider func() map[string]string) {
+	y.providers.mu.Lock()
+	defer y.providers.mu.Unlock()
+	if provider == nil {
+		delete(y.providers.providers, strings.ToLower(keyName))
+		return
+	}
+	y.providers.providers[strings.ToLower(keyName)] = provider
+}
+
+// GetStringMapStringProvided 将提供函数返回的字典与配置文件中的字典合并，同名子键以配置文件为准
+// 配置文件部分走 GetStringMapString 的缓存，提供函数每次读取都会重新调用，因此运行时计算出的项总是最新的
+func (y *yamlConfig) GetStringMapStringProvided(keyName string) map[string]string {
+	if !y.ready(keyName) {
+		return map[string]string{}
+	}
+	y.providers.mu.Lock()
+	provider := y.providers.providers[strings.ToLower(keyName)]
+	y.providers.mu.Unlock()
+
+	value := make(map[string]string)
+	if provider != nil {
+		start := time.Now()
+		provided := provider()
+		y.recordLatency(keyName, start)
+		for subKey, subValue := range provided {
+			value[strings.ToLower(subKey)] = subValue
+		}
+	}
+	for subKey, subValue := range y.GetStringMapString(keyName) {
+		value[subKey] = subValue
+	}
+	return value
+}
@@ -0,0 +1,67 @@
+package yaml_config
+
+import (
+	"apier/internal/global/custom_errors"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// 字典内引用的格式：${子键名}
+var mapReferencePattern = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// GetStringMapStringResolved 与 GetStringMapString 相同，但会将值中的 ${子键名} 替换为同一字典中对应子键的值，引用可以嵌套
+// 例如 {base: http://api, users: ${base}/users}；存在循环引用或引用了不存在的子键时返回错误，解析结果会被缓存
+func (y *yamlConfig) GetStringMapStringResolved(keyName string) (map[string]string, error) {
+	if !y.ready(keyName) {
+		return nil, errors.New(custom_errors.ErrorsConfigNotLoaded + keyName)
+	}
+	cacheKey := keyName + "#resolved"
+	if cached, ok := y.getValueFromCache(cacheKey).(map[string]string); ok {
+		return copyStringMap(cached), nil
+	}
+	gen := y.generation()
+	raw := y.GetStringMapString(keyName)
+	value := make(map[string]string, len(raw))
+	for subKey := range raw {
+		if _, err := resolveMapReference(raw, value, subKey, nil); err != nil {
+			return nil, err
+		}
+	}
+	y.cache(gen, cacheKey, value)
+	return copyStringMap(value), nil
+}
+
+// resolveMapReference 递归解析某个子键的值，resolved 保存已解析完成的子键，chain 为当前的解析路径，用于检测循环
+func resolveMapReference(raw, resolved map[string]string, subKey string, chain []string) (string, error) {
+	if value, exists := resolved[subKey]; exists {
+		return value, nil
+	}
+	for _, visited := range chain {
+		if visited == subKey {
+			return "", errors.New(custom_errors.ErrorsConfigRefCycle + strings.Join(append(chain, subKey), " -> "))
+		}
+	}
+	rawValue, exists := raw[subKey]
+	if !exists {
+		return "", errors.New(custom_errors.ErrorsConfigRefNotExists + subKey)
+	}
+	chain = append(chain, subKey)
+
+	var resolveErr error
+	value := mapReferencePattern.ReplaceAllStringFunc(rawValue, func(ref string) string {
+		if resolveErr != nil {
+			return ref
+		}
+		refValue, err := resolveMapReference(raw, resolved, strings.ToLower(mapReferencePattern.FindStringSubmatch(ref)[1]), chain)
+		if err != nil {
+			resolveErr = err
+		}
+		return refValue
+	})
+	if resolveErr != nil {
+		return "", resolveErr
+	}
+	resolved[subKey] = value
+	return value, nil
+}
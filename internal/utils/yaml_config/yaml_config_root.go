@@ -0,0 +1,135 @@
+package yaml_config
+
+import (
+	"apier/internal/global/custom_errors"
+	"errors"
+	"fmt"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"reflect"
+	"sync"
+)
+
+// 整体绑定配置时使用的解码钩子：在 viper 默认钩子的基础上支持实现了 encoding.TextUnmarshaler 的类型（例如 net.IP）
+var rootDecodeHook = viper.DecodeHook(mapstructure.ComposeDecodeHookFunc(
+	mapstructure.StringToTimeDurationHookFunc(),
+	mapstructure.StringToSliceHookFunc(","),
+	mapstructure.TextUnmarshallerHookFunc(),
+))
+
+// rootBindings LoadInto 登记的绑定目标，每一项都是 *atomic.Pointer[T] 的 Swap 方法与 T 的类型
+// superseded 为重新绑定时被替换下来、尚未交给 onSupersede 的旧值
+type rootBindings struct {
+	mu         sync.Mutex
+	targets    []rootBinding
+	superseded []supersededRoot
+}
+
+type rootBinding struct {
+	swap        reflect.Value
+	rootType    reflect.Type
+	onSupersede func(old interface{})
+}
+
+type supersededRoot struct {
+	onSupersede func(old interface{})
+	old         interface{}
+}
+
+// LoadInto 将全部配置解码为一个根结构体，root 必须是 *atomic.Pointer[T]（T 为结构体），例如：
+//
+//	var appConfig atomic.Pointer[AppConfig]
+//	err := ConfigYaml.LoadInto(&appConfig)
+//	host := appConfig.Load().Redis.Host
+//
+// 每次解码都生成一个新的 *T，结构体带有 binding 标签时先校验再存入，因此读取方拿到的总是一份完整、校验通过的配置
+// 配置重新载入后自动重新解码并原子替换；重新解码或校验失败时保留旧值并记录错误日志
+func (y *yamlConfig) LoadInto(root interface{}) error {
+	return y.LoadIntoWithSupersede(root, nil)
+}
+
+// LoadIntoWithSupersede 与 LoadInto 相同，每次原子替换之后以被替换下来的旧值（*T）调用 onSupersede，用于释放旧配置引用的资源，例如关闭旧的连接
+// 回调时新值已经存入，之后的 Load 不会再读到旧值，但之前已经取得旧值的读取方可能仍在使用；回调在释放实例的锁之后同步执行，可以在回调中读取配置
+// 重新解码或校验失败时不替换，也不调用回调；onSupersede 为 nil 时与 LoadInto 相同
+func (y *yamlConfig) LoadIntoWithSupersede(root interface{}, onSupersede func(old interface{})) error {
+	binding, err := newRootBinding(root)
+	if err != nil {
+		return err
+	}
+	binding.onSupersede = onSupersede
+	y.mu.Lock()
+	old, err := y.bindRoot(binding)
+	if err != nil {
+		y.mu.Unlock()
+		return err
+	}
+	y.roots.mu.Lock()
+	y.roots.targets = append(y.roots.targets, binding)
+	y.roots.mu.Unlock()
+	y.mu.Unlock()
+	if old != nil && onSupersede != nil {
+		onSupersede(old)
+	}
+	return nil
+}
+
+// newRootBinding 检查 root 是否为 *atomic.Pointer[T]，通过 Swap 方法的参数类型取得 T
+func newRootBinding(root interface{}) (rootBinding, error) {
+	rootValue := reflect.ValueOf(root)
+	if rootValue.Kind() != reflect.Ptr || rootValue.IsNil() {
+		return rootBinding{}, errors.New(custom_errors.ErrorsConfigLoadIntoTarget + fmt.Sprintf("%T", root))
+	}
+	swap := rootValue.MethodByName("Swap")
+	if !swap.IsValid() || swap.Type().NumIn() != 1 || swap.Type().NumOut() != 1 || swap.Type().In(0) != swap.Type().Out(0) {
+		return rootBinding{}, errors.New(custom_errors.ErrorsConfigLoadIntoTarget + fmt.Sprintf("%T", root))
+	}
+	argType := swap.Type().In(0)
+	if argType.Kind() != reflect.Ptr || argType.Elem().Kind() != reflect.Struct {
+		return rootBinding{}, errors.New(custom_errors.ErrorsConfigLoadIntoTarget + fmt.Sprintf("%T", root))
+	}
+	return rootBinding{swap: swap, rootType: argType.Elem()}, nil
+}
+
+// bindRoot 解码、校验并存入一份新的根结构体，返回被替换下来的旧值，之前没有值时返回 nil，调用方需持有 y.mu
+func (y *yamlConfig) bindRoot(binding rootBinding) (interface{}, error) {
+	target := reflect.New(binding.rootType)
+	if err := y.viper.Unmarshal(target.Interface(), rootDecodeHook); err != nil {
+		return nil, err
+	}
+	if err := configValidator.Struct(target.Interface()); err != nil {
+		return nil, err
+	}
+	old := binding.swap.Call([]reflect.Value{target})[0]
+	if old.IsNil() {
+		return nil, nil
+	}
+	return old.Interface(), nil
+}
+
+// rebindRoots 重新载入后重新绑定全部根结构体，调用方需持有 y.mu
+func (y *yamlConfig) rebindRoots() {
+	y.roots.mu.Lock()
+	defer y.roots.mu.Unlock()
+	for _, binding := range y.roots.targets {
+		old, err := y.bindRoot(binding)
+		if err != nil {
+			logError(custom_errors.ErrorsConfigReloadFail, zap.String("root", binding.rootType.String()), zap.Error(err))
+			continue
+		}
+		if old != nil && binding.onSupersede != nil {
+			y.roots.superseded = append(y.roots.superseded, supersededRoot{onSupersede: binding.onSupersede, old: old})
+		}
+	}
+}
+
+// dispatchSuperseded 将重新绑定时替换下来的旧值交给各自的 onSupersede，调用方不能持有 y.mu
+func (y *yamlConfig) dispatchSuperseded() {
+	y.roots.mu.Lock()
+	superseded := y.roots.superseded
+	y.roots.superseded = nil
+	y.roots.mu.Unlock()
+	for _, item := range superseded {
+		item.onSupersede(item.old)
+	}
+}
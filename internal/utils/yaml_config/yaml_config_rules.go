@@ -0,0 +1,72 @@
+package yaml_config
+
+import (
+	"apier/internal/global/custom_errors"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"sync"
+)
+
+// requireRule 当 condKey 的值等于 condValue 时，keyName 为必填项
+type requireRule struct {
+	keyName   string
+	condKey   string
+	condValue interface{}
+}
+
+type requireRules struct {
+	mu    sync.Mutex
+	rules []requireRule
+}
+
+// RequireIf 注册一条条件必填规则，例如 tls.enabled 为 true 时 tls.cert 必填，并立即按当前配置校验一次
+// 已注册的规则会在每次重新载入后重新校验，未通过时记录错误日志
+func (y *yamlConfig) RequireIf(keyName, condKey string, condValue interface{}) error {
+	rule := requireRule{keyName: keyName, condKey: condKey, condValue: condValue}
+	y.rules.mu.Lock()
+	y.rules.rules = append(y.rules.rules, rule)
+	y.rules.mu.Unlock()
+
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	return y.checkRule(rule)
+}
+
+// CheckRules 校验全部已注册的规则，返回合并后的错误
+func (y *yamlConfig) CheckRules() error {
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	return y.checkRules()
+}
+
+// checkRules 校验全部规则，调用方需持有 y.mu
+func (y *yamlConfig) checkRules() error {
+	y.rules.mu.Lock()
+	defer y.rules.mu.Unlock()
+	var errs []error
+	for _, rule := range y.rules.rules {
+		if err := y.checkRule(rule); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkRule 条件值按字符串形式比较，调用方需持有 y.mu，兼容环境变量覆盖后 true、"true" 等不同类型的情况
+func (y *yamlConfig) checkRule(rule requireRule) error {
+	if fmt.Sprint(y.viper.Get(rule.condKey)) != fmt.Sprint(rule.condValue) {
+		return nil
+	}
+	if value := y.viper.Get(rule.keyName); value == nil || value == "" {
+		return fmt.Errorf(custom_errors.ErrorsConfigRequiredIf, rule.keyName, rule.condKey, rule.condValue)
+	}
+	return nil
+}
+
+// checkRulesAfterReload 重新载入后校验规则，仅记录日志，不阻止新配置生效
+func (y *yamlConfig) checkRulesAfterReload() {
+	if err := y.checkRules(); err != nil {
+		logError(custom_errors.ErrorsConfigReloadFail, zap.Error(err))
+	}
+}
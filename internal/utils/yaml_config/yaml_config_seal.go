@@ -0,0 +1,78 @@
+package yaml_config
+
+import (
+	"strings"
+	"sync"
+)
+
+// sealState Seal 之后的全量配置快照，键为小写的完整键名（包含各级配置段），settings 为 nil 表示尚未封存
+// lookups 记录调用方原始键名（未转小写）到值的映射，避免每次读取都转换大小写；envPrefix 为封存时 AutomaticEnv 设置的前缀，供 *_FILE 密钥文件使用
+type sealState struct {
+	mu        sync.RWMutex
+	settings  map[string]interface{}
+	lookups   *sync.Map
+	envPrefix string
+}
+
+func (s *sealState) isSealed() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.settings != nil
+}
+
+// Seal 一次性读取全部配置形成只读快照，此后基础 getter 只从快照读取，不再访问 viper，也不再写入容器缓存
+// 适合启动后配置不再变化的部署方式；文件重新载入或 Set 之后会自动重新封存，快照始终与 viper 一致
+func (y *yamlConfig) Seal() {
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	y.seal.mu.Lock()
+	defer y.seal.mu.Unlock()
+	y.seal.settings = flattenSections(y.viper.AllSettings(), "", make(map[string]interface{}))
+	y.seal.lookups = new(sync.Map)
+	y.seal.envPrefix = y.viper.GetEnvPrefix()
+}
+
+// reseal 已封存的实例在配置变化后重新生成快照，调用方需持有 y.mu
+func (y *yamlConfig) reseal() {
+	y.seal.mu.Lock()
+	defer y.seal.mu.Unlock()
+	if y.seal.settings != nil {
+		y.seal.settings = flattenSections(y.viper.AllSettings(), "", make(map[string]interface{}))
+		y.seal.lookups = new(sync.Map)
+		y.seal.envPrefix = y.viper.GetEnvPrefix()
+	}
+}
+
+// flattenSections 展开嵌套配置，叶子键与每一级配置段都以完整键名记录，便于一次查找即可命中
+func flattenSections(settings map[string]interface{}, keyPre string, dst map[string]interface{}) map[string]interface{} {
+	for key, value := range settings {
+		fullKey := keyPre + key
+		dst[fullKey] = value
+		if section, ok := value.(map[string]interface{}); ok {
+			flattenSections(section, fullKey+".", dst)
+		}
+	}
+	return dst
+}
+
+// sealedGet 已封存时从快照读取原始值的深拷贝，调用方修改不会影响快照；sealed 为 false 表示未封存，应走正常读取流程
+func (y *yamlConfig) sealedGet(keyName string) (value interface{}, sealed bool) {
+	y.seal.mu.RLock()
+	defer y.seal.mu.RUnlock()
+	if y.seal.settings == nil {
+		return nil, false
+	}
+	if value, exists := y.seal.lookups.Load(keyName); exists {
+		return deepCopyValue(value), true
+	}
+	value = y.seal.settings[strings.ToLower(y.resolveAlias(keyName))]
+	y.seal.lookups.Store(keyName, value)
+	return deepCopyValue(value), true
+}
+
+// sealedEnvPrefix 已封存时返回封存时的环境变量前缀，sealed 为 false 表示未封存
+func (y *yamlConfig) sealedEnvPrefix() (envPrefix string, sealed bool) {
+	y.seal.mu.RLock()
+	defer y.seal.mu.RUnlock()
+	return y.seal.envPrefix, y.seal.settings != nil
+}
@@ -0,0 +1,147 @@
+package yaml_config
+
+import (
+	"apier/internal/global/custom_errors"
+	"apier/internal/utils/yaml_config/yaml_config_interface"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// 密钥引用的前缀，完整格式：keyring:service/account
+const secretRefPrefix = "keyring:"
+
+// secretStore 密钥解析器以及解析后的明文，明文只保存在实例内存中，不写入全局容器，也不会被持久化
+// fileProfile、fileDirs 为 *_FILE 密钥文件的环境配置，fileEnabled 为 false 时不检查 *_FILE 环境变量
+type secretStore struct {
+	mu          sync.Mutex
+	resolver    yaml_config_interface.SecretResolver
+	decryptor   yaml_config_interface.Decryptor
+	encryptor   yaml_config_interface.Encryptor
+	values      map[string]string
+	fileEnabled bool
+	fileProfile string
+	fileDirs    map[string]string
+}
+
+func newSecretStore(resolver yaml_config_interface.SecretResolver) *secretStore {
+	return &secretStore{resolver: resolver, values: make(map[string]string)}
+}
+
+func isSecretReference(value string) bool {
+	return strings.HasPrefix(value, secretRefPrefix)
+}
+
+// SetSecretResolver 设置密钥解析器，已解析的密钥随之失效
+func (y *yamlConfig) SetSecretResolver(resolver yaml_config_interface.SecretResolver) {
+	y.secrets.mu.Lock()
+	defer y.secrets.mu.Unlock()
+	y.secrets.resolver = resolver
+	y.secrets.values = make(map[string]string)
+}
+
+// SetSecretFileProfile 开启 *_FILE 密钥文件：键 Db.Password 对应的环境变量 DB_PASSWORD_FILE（含 AutomaticEnv 设置的前缀）指向存放密钥的文件
+// 文件路径为相对路径时，相对于当前环境 profile 在 baseDirs 中对应的目录，例如 {"dev": "/run/secrets/dev", "prod": "/run/secrets/prod"}
+func (y *yamlConfig) SetSecretFileProfile(profile string, baseDirs map[string]string) {
+	y.secrets.mu.Lock()
+	defer y.secrets.mu.Unlock()
+	y.secrets.fileEnabled = true
+	y.secrets.fileProfile = profile
+	y.secrets.fileDirs = make(map[string]string, len(baseDirs))
+	for env, dir := range baseDirs {
+		y.secrets.fileDirs[env] = dir
+	}
+	y.secrets.values = make(map[string]string)
+}
+
+// secretFilePath 键对应的 *_FILE 环境变量存在时，返回解析后的密钥文件路径
+func (y *yamlConfig) secretFilePath(keyName string) (string, bool) {
+	// 先读取环境变量前缀再获取 secrets.mu，保持先 y.mu 后 secrets.mu 的加锁顺序；已封存时使用封存时的前缀，不再访问 viper
+	envPrefix, sealed := y.sealedEnvPrefix()
+	if !sealed {
+		y.mu.Lock()
+		envPrefix = y.viper.GetEnvPrefix()
+		y.mu.Unlock()
+	}
+
+	y.secrets.mu.Lock()
+	defer y.secrets.mu.Unlock()
+	if !y.secrets.fileEnabled {
+		return "", false
+	}
+	filePath := os.Getenv(envKeyName(envPrefix, keyName) + "_FILE")
+	if filePath == "" {
+		return "", false
+	}
+	if !filepath.IsAbs(filePath) {
+		filePath = filepath.Join(y.secrets.fileDirs[y.secrets.fileProfile], filePath)
+	}
+	return filePath, true
+}
+
+// resolveFile 读取密钥文件内容，去掉末尾换行，结果与 keyring 引用一样只缓存在内存中
+func (s *secretStore) resolveFile(filePath string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cacheKey := "file:" + filePath
+	if value, exists := s.values[cacheKey]; exists {
+		return value, nil
+	}
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimRight(string(content), "\r\n")
+	s.values[cacheKey] = value
+	return value, nil
+}
+
+func (s *secretStore) getResolver() yaml_config_interface.SecretResolver {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.resolver
+}
+
+// resolve 解析密钥引用，解析成功的结果会缓存，失败不缓存，下次读取时重试
+func (s *secretStore) resolve(ref string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if value, exists := s.values[ref]; exists {
+		return value, nil
+	}
+	if s.resolver == nil {
+		return "", errors.New(custom_errors.ErrorsConfigNoSecretResolver + ref)
+	}
+	service, account, found := strings.Cut(strings.TrimPrefix(ref, secretRefPrefix), "/")
+	if !found || service == "" || account == "" {
+		return "", errors.New(custom_errors.ErrorsConfigSecretRefInvalid + ref)
+	}
+	value, err := s.resolver.ResolveSecret(service, account)
+	if err != nil {
+		return "", err
+	}
+	s.values[ref] = value
+	return value, nil
+}
+
+// clear 清空已解析的密钥，配置重新载入后引用可能已经变化
+func (s *secretStore) clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values = make(map[string]string)
+}
+
+// cloneSettings 拷贝解析器、加解密器与 *_FILE 配置，不包含已解析的明文
+func (s *secretStore) cloneSettings() *secretStore {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	dst := newSecretStore(s.resolver)
+	dst.decryptor = s.decryptor
+	dst.encryptor = s.encryptor
+	dst.fileEnabled = s.fileEnabled
+	dst.fileProfile = s.fileProfile
+	dst.fileDirs = s.fileDirs
+	return dst
+}
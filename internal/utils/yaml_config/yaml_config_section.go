@@ -0,0 +1,36 @@
+package yaml_config
+
+import (
+	"apier/internal/utils/yaml_config/yaml_config_interface"
+)
+
+// section 以配置段键名为前缀读取子项，读取仍然经过配置实例的 getter，缓存规则与直接读取完整键名完全一致
+type section struct {
+	y      yaml_config_interface.YamlConfigInterface
+	prefix string
+}
+
+// GetSection 返回某个配置段的读取器，模块只需关心段内的相对键名
+func (y *yamlConfig) GetSection(keyName string) yaml_config_interface.Section {
+	return &section{y: y, prefix: keyName + "."}
+}
+
+// Get 一个原始值
+func (s *section) Get(keyName string) interface{} {
+	return s.y.Get(s.prefix + keyName)
+}
+
+// GetString 字符串格式返回值
+func (s *section) GetString(keyName string) string {
+	return s.y.GetString(s.prefix + keyName)
+}
+
+// GetInt 整数格式返回值
+func (s *section) GetInt(keyName string) int {
+	return s.y.GetInt(s.prefix + keyName)
+}
+
+// GetBool 布尔格式返回值
+func (s *section) GetBool(keyName string) bool {
+	return s.y.GetBool(s.prefix + keyName)
+}
@@ -0,0 +1,35 @@
+package yaml_config
+
+import (
+	"apier/internal/global/custom_errors"
+	"context"
+	"go.uber.org/zap"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ListenSignals 监听 SIGHUP 信号并重新载入配置，ctx 取消后停止监听并注销信号处理
+// 可与 ConfigFileChangeListen 同时开启，二者最终都通过 Reload 清空缓存
+func (y *yamlConfig) ListenSignals(ctx context.Context) {
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(sigChan)
+		y.listenReload(ctx, sigChan)
+	}()
+}
+
+// listenReload 每收到一次触发就执行一次 Reload，直到 ctx 被取消
+func (y *yamlConfig) listenReload(ctx context.Context, trigger <-chan os.Signal) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-trigger:
+			if err := y.Reload(); err != nil {
+				logError(custom_errors.ErrorsConfigReloadFail, zap.Error(err))
+			}
+		}
+	}
+}
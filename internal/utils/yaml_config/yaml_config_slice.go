@@ -0,0 +1,51 @@
+package yaml_config
+
+import (
+	"apier/internal/global/custom_errors"
+	"apier/internal/utils/yaml_config/yaml_config_interface"
+	"errors"
+	"fmt"
+	"github.com/spf13/cast"
+	"reflect"
+)
+
+// GetSliceOf 读取列表配置项，并用 conv 逐个转换元素，例如：
+//
+//	ports, err := yaml_config.GetSliceOf(variable.ConfigYaml, "Http.Ports", cast.ToIntE)
+//	timeouts, err := yaml_config.GetSliceOf(variable.ConfigYaml, "Retry.Backoff", cast.ToDurationE)
+//
+// 转换失败的元素不出现在结果中，其错误（带元素下标）汇总后一并返回；键不存在时返回空列表，值不是列表时返回错误
+func GetSliceOf[T any](y yaml_config_interface.YamlConfigInterface, keyName string, conv func(interface{}) (T, error)) ([]T, error) {
+	value := y.Get(keyName)
+	if value == nil {
+		return []T{}, nil
+	}
+	items, err := toInterfaceSlice(value)
+	if err != nil {
+		return nil, fmt.Errorf(custom_errors.ErrorsConfigValueTypeMismatch, keyName, "[]interface{}", err.Error())
+	}
+	result := make([]T, 0, len(items))
+	var errs []error
+	for i, item := range items {
+		converted, err := conv(item)
+		if err != nil {
+			errs = append(errs, fmt.Errorf(custom_errors.ErrorsConfigSliceElement, keyName, i, err))
+			continue
+		}
+		result = append(result, converted)
+	}
+	return result, errors.Join(errs...)
+}
+
+// toInterfaceSlice 将任意类型的切片转为 []interface{}；Get 可能返回 GetStringSlice 等 getter 缓存的 []string 之类的具体类型
+func toInterfaceSlice(value interface{}) ([]interface{}, error) {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return cast.ToSliceE(value)
+	}
+	items := make([]interface{}, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items, nil
+}
@@ -0,0 +1,28 @@
+package yaml_config
+
+import (
+	"apier/internal/global/custom_errors"
+	"apier/internal/utils/yaml_config/yaml_config_interface"
+	"errors"
+	"io"
+	"os"
+)
+
+// 标准输入，测试时可以替换为其它 io.Reader
+var stdinReader io.Reader = os.Stdin
+
+// CreateYamlFactoryFromStdin 从标准输入读取配置，供 cat config.yml | tool 这类管道用法使用，format 为 yml、json、toml 等，默认为 yml
+// 标准输入只能读取一次，因此该实例不支持文件监听与重新载入，ConfigFileChangeListen 只记录告警，Reload 返回错误
+func CreateYamlFactoryFromStdin(format string) (yaml_config_interface.YamlConfigInterface, error) {
+	if format == "" {
+		format = "yml"
+	}
+	ymlConfig := newYamlConfig("stdin")
+	ymlConfig.viper.SetConfigType(format)
+	ymlConfig.stdin = true
+	if err := ymlConfig.viper.ReadConfig(stdinReader); err != nil {
+		return nil, errors.New(custom_errors.ErrorsConfigInitFail + err.Error())
+	}
+	ymlConfig.markLoaded()
+	return ymlConfig, nil
+}
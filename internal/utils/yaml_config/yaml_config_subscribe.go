@@ -0,0 +1,87 @@
+package yaml_config
+
+import (
+	"reflect"
+	"sync"
+)
+
+// keySubscription 单个键的变化订阅，last 记录最近一次推送（或订阅时）的值
+type keySubscription struct {
+	keyName string
+	ch      chan interface{}
+	last    interface{}
+}
+
+// keySubscribers 一个配置实例上的全部订阅
+type keySubscribers struct {
+	mu     sync.Mutex
+	nextId int
+	subs   map[int]*keySubscription
+}
+
+func newKeySubscribers() *keySubscribers {
+	return &keySubscribers{subs: make(map[int]*keySubscription)}
+}
+
+// SubscribeKey 订阅某个键的变化，每次重新载入后值发生变化时，新值会被推送到返回的通道
+// 通道只保留最新的一个值，消费不及时不会阻塞文件监听；调用返回的函数或 Close 后通道被关闭
+func (y *yamlConfig) SubscribeKey(keyName string) (<-chan interface{}, func()) {
+	sub := &keySubscription{
+		keyName: keyName,
+		ch:      make(chan interface{}, 1),
+		last:    deepCopyValue(y.viperGet(keyName)),
+	}
+	y.subscribers.mu.Lock()
+	id := y.subscribers.nextId
+	y.subscribers.nextId++
+	y.subscribers.subs[id] = sub
+	y.subscribers.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			y.subscribers.mu.Lock()
+			defer y.subscribers.mu.Unlock()
+			if _, exists := y.subscribers.subs[id]; exists {
+				delete(y.subscribers.subs, id)
+				close(sub.ch)
+			}
+		})
+	}
+	return sub.ch, unsubscribe
+}
+
+// Close 关闭该实例上的全部订阅通道
+func (y *yamlConfig) Close() {
+	y.subscribers.mu.Lock()
+	defer y.subscribers.mu.Unlock()
+	for id, sub := range y.subscribers.subs {
+		delete(y.subscribers.subs, id)
+		close(sub.ch)
+	}
+}
+
+// notifyKeyChanges 配置重新载入后，对比订阅键的新旧值并推送发生变化的键，调用方需持有 y.mu
+func (y *yamlConfig) notifyKeyChanges() {
+	y.subscribers.mu.Lock()
+	defer y.subscribers.mu.Unlock()
+	for _, sub := range y.subscribers.subs {
+		value := y.viper.Get(sub.keyName)
+		if reflect.DeepEqual(sub.last, value) {
+			continue
+		}
+		sub.last = deepCopyValue(value)
+		// 推送拷贝，订阅者修改收到的字典、切片不会影响 viper 内部的配置
+		value = deepCopyValue(value)
+		select {
+		case sub.ch <- value:
+		default:
+			// 丢弃尚未消费的旧值，只保留最新值
+			select {
+			case <-sub.ch:
+			default:
+			}
+			sub.ch <- value
+		}
+	}
+}
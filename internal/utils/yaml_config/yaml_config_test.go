@@ -0,0 +1,2507 @@
+package yaml_config
+
+import (
+	"apier/internal/global/variable"
+	"apier/internal/utils/yaml_config/yaml_config_interface"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/spf13/cast"
+	"github.com/spf13/pflag"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// newTestConfig 在临时目录写入 configs/<name>.yml 并创建配置实例
+func newTestConfig(t *testing.T, name, content string) *yamlConfig {
+	t.Helper()
+	basePath := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(basePath, "configs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	oldBasePath := variable.BasePath
+	variable.BasePath = basePath
+	t.Cleanup(func() { variable.BasePath = oldBasePath })
+	writeTestConfig(t, basePath, name, content)
+
+	y := CreateYamlFactory(name).(*yamlConfig)
+	y.clearCache()
+	t.Cleanup(y.clearCache)
+	return y
+}
+
+// writeTestConfig 覆盖写入临时目录下的配置文件
+func writeTestConfig(t *testing.T, basePath, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(basePath, "configs", name+".yml"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// waitFor 在超时时间内轮询条件是否成立
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("condition not met before timeout")
+}
+
+func TestListenReloadOnTrigger(t *testing.T) {
+	y := newTestConfig(t, "config", "App:\n  Name: before\n")
+	if got := y.GetString("App.Name"); got != "before" {
+		t.Fatalf("GetString = %q, want before", got)
+	}
+	writeTestConfig(t, variable.BasePath, "config", "App:\n  Name: after\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	trigger := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	go func() {
+		y.listenReload(ctx, trigger)
+		close(done)
+	}()
+	trigger <- syscall.SIGHUP
+
+	waitFor(t, func() bool { return y.GetString("App.Name") == "after" })
+	cancel()
+	<-done
+}
+
+func TestConfigFileChangeListenReloads(t *testing.T) {
+	y := newTestConfig(t, "config", "App:\n  Name: before\n")
+	if got := y.GetString("App.Name"); got != "before" {
+		t.Fatalf("GetString = %q, want before", got)
+	}
+	y.ConfigFileChangeListen()
+	writeTestConfig(t, variable.BasePath, "config", "App:\n  Name: after\n")
+
+	waitFor(t, func() bool { return y.GetString("App.Name") == "after" })
+}
+
+func TestGetStringMapReturnsIsolatedCopy(t *testing.T) {
+	y := newTestConfig(t, "config", "Db:\n  Master:\n    Host: 127.0.0.1\n    Ports: [3306, 3307]\n")
+
+	first := y.GetStringMap("Db")
+	master := first["master"].(map[string]interface{})
+	master["host"] = "mutated"
+	master["ports"].([]interface{})[0] = 0
+
+	second := y.GetStringMap("Db")
+	master = second["master"].(map[string]interface{})
+	if master["host"] != "127.0.0.1" {
+		t.Fatalf("nested map mutated through cache: host = %v", master["host"])
+	}
+	if master["ports"].([]interface{})[0] != 3306 {
+		t.Fatalf("nested slice mutated through cache: ports = %v", master["ports"])
+	}
+}
+
+func TestGetStringMapStringReturnsIsolatedCopy(t *testing.T) {
+	y := newTestConfig(t, "config", "Labels:\n  Env: dev\n")
+
+	y.GetStringMapString("Labels")["env"] = "mutated"
+	if got := y.GetStringMapString("Labels")["env"]; got != "dev" {
+		t.Fatalf("GetStringMapString env = %q, want dev", got)
+	}
+}
+
+func TestBindPFlagsOverridesFileValue(t *testing.T) {
+	y := newTestConfig(t, "config", "App:\n  Name: from_file\n")
+	if got := y.GetString("App.Name"); got != "from_file" {
+		t.Fatalf("GetString = %q, want from_file", got)
+	}
+
+	flagSet := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flagSet.String("App.Name", "", "app name")
+	if err := flagSet.Parse([]string{"--App.Name=from_flag"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := y.BindPFlags(flagSet); err != nil {
+		t.Fatal(err)
+	}
+	if got := y.GetString("App.Name"); got != "from_flag" {
+		t.Fatalf("GetString = %q, want from_flag", got)
+	}
+}
+
+func TestGetStringMapStringStrict(t *testing.T) {
+	y := newTestConfig(t, "config", "Labels:\n  Env: dev\n  Team: core\nPorts:\n  Api: \"20191\"\n  Web: 20201\n")
+
+	labels, err := y.GetStringMapStringStrict("Labels")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if labels["env"] != "dev" || labels["team"] != "core" {
+		t.Fatalf("GetStringMapStringStrict = %v", labels)
+	}
+
+	_, err = y.GetStringMapStringStrict("Ports")
+	if err == nil || !strings.Contains(err.Error(), "Ports.web") {
+		t.Fatalf("expected error naming Ports.web, got %v", err)
+	}
+}
+
+func TestWarmupCachesKeys(t *testing.T) {
+	y := newTestConfig(t, "config", "App:\n  Name: apier\n  Port: 8080\n")
+	before := containerFactory.Count(y.cachePrefix)
+
+	y.Warmup("App.Name", "App.Port", "App.Missing")
+
+	if got := containerFactory.Count(y.cachePrefix); got != before+2 {
+		t.Fatalf("Count = %d, want %d", got, before+2)
+	}
+	cached := strings.Join(containerFactory.Keys(y.cachePrefix), ",")
+	for _, keyName := range []string{"App.Name", "App.Port"} {
+		if !strings.Contains(cached, y.cachePrefix+keyName) {
+			t.Fatalf("key %s not cached, cached keys: %s", keyName, cached)
+		}
+	}
+	if y.keyIsCache("App.Missing") {
+		t.Fatal("missing key should not be cached")
+	}
+}
+
+func TestBuildInverted(t *testing.T) {
+	y := newTestConfig(t, "config", "Roles:\n  Admin: [read, write]\n  Viewer: [read]\n")
+
+	inverted := y.BuildInverted("Roles")
+	if got := strings.Join(inverted["read"], ","); got != "admin,viewer" {
+		t.Fatalf("read roles = %q, want admin,viewer", got)
+	}
+	if got := strings.Join(inverted["write"], ","); got != "admin" {
+		t.Fatalf("write roles = %q, want admin", got)
+	}
+
+	writeTestConfig(t, variable.BasePath, "config", "Roles:\n  Viewer: [read, write]\n")
+	if err := y.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(y.BuildInverted("Roles")["write"], ","); got != "viewer" {
+		t.Fatalf("write roles after reload = %q, want viewer", got)
+	}
+}
+
+func TestCheckVersion(t *testing.T) {
+	y := newTestConfig(t, "config", "config_version: 2\n")
+	if err := y.CheckVersion(2); err != nil {
+		t.Fatalf("matching version: unexpected error %v", err)
+	}
+	if err := y.CheckVersion(3); err == nil {
+		t.Fatal("mismatching version: expected error")
+	}
+
+	y = newTestConfig(t, "config", "App:\n  Name: apier\n")
+	if err := y.CheckVersion(2); err == nil {
+		t.Fatal("missing version: expected error")
+	}
+}
+
+func TestSubscribeKey(t *testing.T) {
+	y := newTestConfig(t, "config", "App:\n  Name: before\n  Port: 8080\n")
+	ch, unsubscribe := y.SubscribeKey("App.Name")
+
+	writeTestConfig(t, variable.BasePath, "config", "App:\n  Name: after\n  Port: 8080\n")
+	if err := y.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case value := <-ch:
+		if value != "after" {
+			t.Fatalf("received %v, want after", value)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no value received after change")
+	}
+
+	unsubscribe()
+	if _, ok := <-ch; ok {
+		t.Fatal("channel should be closed after unsubscribe")
+	}
+	unsubscribe()
+
+	// 字典类型的值推送的是拷贝，订阅者修改不影响生效配置
+	ch, _ = y.SubscribeKey("App.Labels")
+	writeTestConfig(t, variable.BasePath, "config", "App:\n  Name: after\n  Port: 8080\n  Labels:\n    Env: dev\n")
+	if err := y.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case value := <-ch:
+		value.(map[string]interface{})["env"] = "mutated"
+	case <-time.After(time.Second):
+		t.Fatal("no value received for App.Labels")
+	}
+	if got := y.Get("App.Labels.Env"); got != "dev" {
+		t.Fatalf("App.Labels.Env = %v after subscriber mutation, want dev", got)
+	}
+
+	ch, _ = y.SubscribeKey("App.Port")
+	y.Close()
+	if _, ok := <-ch; ok {
+		t.Fatal("channel should be closed after Close")
+	}
+}
+
+func TestGetOrderedMapKeys(t *testing.T) {
+	y := newTestConfig(t, "config", "Services:\n  Web: 1\n  Api: 2\n  Cron: 3\n  Mq: 4\n")
+	if got := strings.Join(y.GetOrderedMapKeys("Services"), ","); got != "api,cron,mq,web" {
+		t.Fatalf("GetOrderedMapKeys = %q, want api,cron,mq,web", got)
+	}
+}
+
+func TestLoadWithReportCollectsAllIssues(t *testing.T) {
+	newTestConfig(t, "report", "App:\n  Port: abc\n  Debug: maybe\n")
+
+	ymlConfig, errs := LoadWithReport(LoadOptions{
+		FileName:     "report",
+		RequiredKeys: []string{"App.Port", "App.Name"},
+		Types:        map[string]string{"App.Port": "int", "App.Debug": "bool"},
+	})
+	if ymlConfig == nil {
+		t.Fatal("expected partial config instance")
+	}
+	if len(errs) != 3 {
+		t.Fatalf("expected 3 issues, got %d: %v", len(errs), errs)
+	}
+	for i, want := range []string{"App.Name", "App.Debug", "App.Port"} {
+		if !strings.Contains(errs[i].Error(), want) {
+			t.Fatalf("issue %d = %q, want it to mention %s", i, errs[i], want)
+		}
+	}
+
+	if _, errs = LoadWithReport(LoadOptions{FileName: "missing", RequiredKeys: []string{"App.Name"}}); len(errs) != 2 {
+		t.Fatalf("expected read error and missing key, got %v", errs)
+	}
+}
+
+func TestGetRawSections(t *testing.T) {
+	y := newTestConfig(t, "config", "Services:\n  Order:\n    Url: http://order\n    Retry: 3\n  User:\n    Url: http://user\n    Tags: [a, b]\n")
+
+	sections, err := y.GetRawSections("Services")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sections) != 2 {
+		t.Fatalf("expected 2 sections, got %d", len(sections))
+	}
+	var order struct {
+		Url   string
+		Retry int
+	}
+	if err := json.Unmarshal(sections["order"], &order); err != nil {
+		t.Fatalf("order section is not valid json: %v", err)
+	}
+	if order.Url != "http://order" || order.Retry != 3 {
+		t.Fatalf("order section = %+v", order)
+	}
+	var user map[string]interface{}
+	if err := json.Unmarshal(sections["user"], &user); err != nil {
+		t.Fatalf("user section is not valid json: %v", err)
+	}
+	if len(user["tags"].([]interface{})) != 2 {
+		t.Fatalf("user section = %v", user)
+	}
+	if !y.keyIsCache("Services#raw") {
+		t.Fatal("raw sections should be cached")
+	}
+}
+
+func TestDiffAgainstFile(t *testing.T) {
+	y := newTestConfig(t, "config", "App:\n  Name: apier\n  Port: 9090\n  Debug: true\n")
+	writeTestConfig(t, variable.BasePath, "config.ref", "App:\n  Name: apier\n  Port: 8080\n  Timeout: 30\n")
+
+	changes, err := y.DiffAgainstFile("config.ref")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []yaml_config_interface.ChangedKey{
+		{Key: "app.debug", Type: yaml_config_interface.KeyAdded, NewValue: true},
+		{Key: "app.port", Type: yaml_config_interface.KeyChanged, OldValue: 8080, NewValue: 9090},
+		{Key: "app.timeout", Type: yaml_config_interface.KeyRemoved, OldValue: 30},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Fatalf("DiffAgainstFile = %+v, want %+v", changes, want)
+	}
+
+	if _, err := y.DiffAgainstFile("missing"); err == nil {
+		t.Fatal("expected error for missing reference file")
+	}
+}
+
+func TestGetStringMapStringAppliesEnvPerKey(t *testing.T) {
+	y := newTestConfig(t, "config", "Db:\n  Host: 127.0.0.1\n  Port: \"3306\"\n")
+	t.Setenv("APIER_DB_HOST", "10.0.0.8")
+	y.AutomaticEnv("APIER")
+
+	db := y.GetStringMapString("Db")
+	if db["host"] != "10.0.0.8" {
+		t.Fatalf("host = %q, want env override 10.0.0.8", db["host"])
+	}
+	if db["port"] != "3306" {
+		t.Fatalf("port = %q, want file value 3306", db["port"])
+	}
+}
+
+func TestCloneCacheIsolation(t *testing.T) {
+	y := newTestConfig(t, "config", "App:\n  Name: parent\n")
+	writeTestConfig(t, variable.BasePath, "isolated", "App:\n  Name: isolated\n")
+	writeTestConfig(t, variable.BasePath, "shared", "Db:\n  Host: before\n")
+
+	isolated := y.Clone("isolated").(*yamlConfig)
+	shared := y.CloneShared("shared").(*yamlConfig)
+	t.Cleanup(isolated.clearCache)
+
+	if y.GetString("App.Name") != "parent" || isolated.GetString("App.Name") != "isolated" {
+		t.Fatal("isolated clone must not share cached values with the parent")
+	}
+	if shared.GetString("Db.Host") != "before" {
+		t.Fatal("shared clone read the wrong value")
+	}
+
+	writeTestConfig(t, variable.BasePath, "shared", "Db:\n  Host: after\n")
+	if err := shared.viper.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if err := y.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if shared.keyIsCache("Db.Host") {
+		t.Fatal("reloading the parent should invalidate the shared clone's cache")
+	}
+	if !isolated.keyIsCache("App.Name") {
+		t.Fatal("reloading the parent should not invalidate the isolated clone's cache")
+	}
+	if got := shared.GetString("Db.Host"); got != "after" {
+		t.Fatalf("shared clone Db.Host = %q, want after", got)
+	}
+}
+
+func TestNumericGettersTrimEnvValues(t *testing.T) {
+	y := newTestConfig(t, "config", "App:\n  Port: 8080\n  Timeout: 10s\n  Ratio: 0.5\n")
+	t.Setenv("APP_PORT", ` "9090" `)
+	t.Setenv("APP_TIMEOUT", " '30s'\n")
+	t.Setenv("APP_RATIO", "  0.75 ")
+	y.AutomaticEnv("")
+
+	if got := y.GetInt("App.Port"); got != 9090 {
+		t.Fatalf("GetInt = %d, want 9090", got)
+	}
+	if got := y.GetDuration("App.Timeout"); got != 30*time.Second {
+		t.Fatalf("GetDuration = %v, want 30s", got)
+	}
+	if got := y.GetFloat64("App.Ratio"); got != 0.75 {
+		t.Fatalf("GetFloat64 = %v, want 0.75", got)
+	}
+}
+
+func TestFreezeKeysPinsValueAcrossReload(t *testing.T) {
+	y := newTestConfig(t, "config", "Security:\n  JwtKey: original\nApp:\n  Name: before\n")
+	y.FreezeKeys("Security.JwtKey")
+
+	if err := y.Set("Security.JwtKey", "changed"); err == nil {
+		t.Fatal("Set on a frozen key should fail")
+	}
+
+	writeTestConfig(t, variable.BasePath, "config", "Security:\n  JwtKey: rotated\nApp:\n  Name: after\n")
+	if err := y.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := y.GetString("Security.JwtKey"); got != "original" {
+		t.Fatalf("frozen key = %q, want original", got)
+	}
+	if got := y.GetString("App.Name"); got != "after" {
+		t.Fatalf("unfrozen key = %q, want after", got)
+	}
+	if err := y.Set("App.Name", "set"); err != nil || y.GetString("App.Name") != "set" {
+		t.Fatalf("Set on an unfrozen key failed: %v", err)
+	}
+}
+
+func TestMergeMaps(t *testing.T) {
+	y := newTestConfig(t, "config", "Labels:\n  Common:\n    Team: core\n    Env: dev\n  Service:\n    Env: prod\n    App: apier\n")
+
+	merged := y.MergeMaps("Labels.Common", "Labels.Service")
+	want := map[string]string{"team": "core", "env": "prod", "app": "apier"}
+	if !reflect.DeepEqual(merged, want) {
+		t.Fatalf("MergeMaps = %v, want %v", merged, want)
+	}
+	if !y.keyIsCache("Labels.Common,Labels.Service#merged") {
+		t.Fatal("merged map should be cached")
+	}
+}
+
+func TestVerifyCacheTypes(t *testing.T) {
+	y := newTestConfig(t, "config", "App:\n  Name: apier\n  Port: 8080\n")
+	y.DeclareTypes(map[string]string{"App.Name": "string", "App.Port": "int"})
+
+	y.GetString("App.Name")
+	y.cache(y.generation(), "App.Port", "8080")
+
+	errs := y.VerifyCacheTypes()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "App.Port") {
+		t.Fatalf("VerifyCacheTypes = %v, want a single App.Port mismatch", errs)
+	}
+}
+
+// fakeSecretResolver 以 service/account 为键的内存密钥存储，记录解析次数
+type fakeSecretResolver struct {
+	secrets map[string]string
+	calls   int
+}
+
+func (f *fakeSecretResolver) ResolveSecret(service, account string) (string, error) {
+	f.calls++
+	if value, exists := f.secrets[service+"/"+account]; exists {
+		return value, nil
+	}
+	return "", errors.New("secret not found: " + service + "/" + account)
+}
+
+func TestSecretResolver(t *testing.T) {
+	y := newTestConfig(t, "config", "Db:\n  Password: keyring:apier/db\n  Token: keyring:apier/missing\n  User: root\n")
+	resolver := &fakeSecretResolver{secrets: map[string]string{"apier/db": "s3cret"}}
+	y.SetSecretResolver(resolver)
+
+	for i := 0; i < 2; i++ {
+		if got := y.GetString("Db.Password"); got != "s3cret" {
+			t.Fatalf("GetString = %q, want s3cret", got)
+		}
+	}
+	if resolver.calls != 1 {
+		t.Fatalf("resolver called %d times, want 1", resolver.calls)
+	}
+	if cached := y.getValueFromCache("Db.Password"); cached != "keyring:apier/db" {
+		t.Fatalf("container must only hold the reference, got %v", cached)
+	}
+
+	if _, err := y.GetStringE("Db.Token"); err == nil {
+		t.Fatal("expected error for missing secret")
+	}
+	if got := y.GetString("Db.Token"); got != "" {
+		t.Fatalf("GetString for missing secret = %q, want empty", got)
+	}
+	if got := y.GetString("Db.User"); got != "root" {
+		t.Fatalf("plain value = %q, want root", got)
+	}
+}
+
+func TestGetStringSliceFlowAndBlockStyles(t *testing.T) {
+	y := newTestConfig(t, "config", "Proxy:\n  Flow: [192.168.10.1, \"192.168.10.2\"]\n  Block:\n    - 192.168.10.1\n    - \"192.168.10.2\"\n")
+
+	flow := y.GetStringSlice("Proxy.Flow")
+	block := y.GetStringSlice("Proxy.Block")
+	want := []string{"192.168.10.1", "192.168.10.2"}
+	if !reflect.DeepEqual(flow, want) || !reflect.DeepEqual(block, want) {
+		t.Fatalf("flow = %v, block = %v, want both %v", flow, block, want)
+	}
+}
+
+func TestGetStringChecked(t *testing.T) {
+	y := newTestConfig(t, "config", "Mail:\n  Admin: admin@apier.dev\n  Support: not-an-email\n")
+	isEmail := func(value string) error {
+		if !strings.Contains(value, "@") {
+			return errors.New("invalid email: " + value)
+		}
+		return nil
+	}
+
+	if value, err := y.GetStringChecked("Mail.Admin", isEmail); err != nil || value != "admin@apier.dev" {
+		t.Fatalf("GetStringChecked = %q, %v", value, err)
+	}
+	if _, err := y.GetStringChecked("Mail.Support", isEmail); err == nil {
+		t.Fatal("expected predicate error")
+	}
+}
+
+func TestGetStringMapExtends(t *testing.T) {
+	y := newTestConfig(t, "config", `_base:
+  Host: 127.0.0.1
+  Port: 3306
+  Pool:
+    Max: 10
+    Idle: 2
+Dev:
+  _extends: _base
+  Port: 3307
+Test:
+  _extends: Dev
+  Pool:
+    Max: 5
+LoopA:
+  _extends: LoopB
+LoopB:
+  _extends: LoopA
+`)
+
+	dev := y.GetStringMap("Dev")
+	if dev["host"] != "127.0.0.1" || dev["port"] != 3307 {
+		t.Fatalf("single inheritance = %v", dev)
+	}
+	if _, exists := dev["_extends"]; exists {
+		t.Fatal("_extends should not appear in the resolved section")
+	}
+
+	test := y.GetStringMap("Test")
+	pool := test["pool"].(map[string]interface{})
+	if test["host"] != "127.0.0.1" || test["port"] != 3307 || pool["max"] != 5 || pool["idle"] != 2 {
+		t.Fatalf("chained inheritance = %v", test)
+	}
+
+	if _, err := y.GetStringMapE("LoopA"); err == nil || !strings.Contains(err.Error(), "loopa -> loopb -> loopa") {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+
+	// Get 先在同一键名下缓存了未合并的原始字典
+	base := newTestConfig(t, "config", "_base:\n  Host: 127.0.0.1\nDev:\n  _extends: _base\n  Port: 1\n")
+	if raw := base.Get("Dev").(map[string]interface{}); raw["_extends"] != "_base" {
+		t.Fatalf("Get should return the raw section, got %v", raw)
+	}
+	if got := base.GetStringMap("Dev"); !reflect.DeepEqual(got, map[string]interface{}{"host": "127.0.0.1", "port": 1}) {
+		t.Fatalf("GetStringMap after Get = %v", got)
+	}
+	if raw := base.Get("Dev").(map[string]interface{}); raw["_extends"] != "_base" {
+		t.Fatalf("Get after GetStringMap should still return the raw section, got %v", raw)
+	}
+}
+
+func TestMaskingOnlyAppliesToExportPaths(t *testing.T) {
+	y := newTestConfig(t, "config", "Redis:\n  Host: 127.0.0.1\n  Password: s3cret\n  Auth:\n    User: root\n")
+
+	redis := y.GetStringMap("Redis")
+	if redis["password"] != "s3cret" {
+		t.Fatalf("GetStringMap must return the real secret, got %v", redis["password"])
+	}
+
+	masked := y.AllSettingsMasked()["redis"].(map[string]interface{})
+	if masked["password"] != maskedValue || masked["auth"] != maskedValue || masked["host"] != "127.0.0.1" {
+		t.Fatalf("AllSettingsMasked = %v", masked)
+	}
+
+	dump := y.DebugString()
+	if strings.Contains(dump, "s3cret") || strings.Contains(dump, "root") {
+		t.Fatalf("DebugString leaked a secret:\n%s", dump)
+	}
+	if !strings.Contains(dump, "redis.host = 127.0.0.1") || !strings.Contains(dump, "redis.password = "+maskedValue) {
+		t.Fatalf("DebugString = \n%s", dump)
+	}
+	if y.GetStringMap("Redis")["password"] != "s3cret" {
+		t.Fatal("dumping must not alter the cached real value")
+	}
+}
+
+func TestSealedReadsBypassViper(t *testing.T) {
+	y := newTestConfig(t, "config", "App:\n  Name: apier\n  Port: \"8080\"\n  Timeout: 30s\n  Hosts: [a, b]\n  Labels:\n    Env: dev\n  Dir: logs\n  Notes: |\n    a\n    b\n  Roles:\n    admin: [read]\n")
+	y.SetSecretFileProfile("dev", nil)
+	y.Seal()
+
+	// 所有 viper 访问都持有 y.mu，封存后以计数的锁替换，读取过程中一次都不应获取
+	locker := &countingLocker{Locker: y.mu}
+	y.mu = locker
+	defer func() { y.mu = locker.Locker }()
+
+	if got := y.GetString("App.Name"); got != "apier" {
+		t.Fatalf("GetString = %q", got)
+	}
+	if got := y.GetInt("App.Port"); got != 8080 {
+		t.Fatalf("GetInt = %d", got)
+	}
+	if got := y.GetDuration("App.Timeout"); got != 30*time.Second {
+		t.Fatalf("GetDuration = %v", got)
+	}
+	if got := y.GetStringSlice("App.Hosts"); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("GetStringSlice = %v", got)
+	}
+	if got := y.GetStringMapString("App.Labels"); got["env"] != "dev" {
+		t.Fatalf("GetStringMapString = %v", got)
+	}
+	if got := y.GetStringMap("App")["name"]; got != "apier" {
+		t.Fatalf("GetStringMap = %v", got)
+	}
+	if got := y.GetString("App.Missing"); got != "" {
+		t.Fatalf("missing key = %q, want empty", got)
+	}
+
+	// 派生的 getter 同样只读取快照
+	if got := y.GetStringSet("App.Hosts"); len(got) != 2 {
+		t.Fatalf("GetStringSet = %v", got)
+	}
+	if got := y.GetLines("App.Notes"); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("GetLines = %v", got)
+	}
+	if got := y.GetPath("App.Dir"); got != filepath.Join(variable.BasePath, "logs") {
+		t.Fatalf("GetPath = %q", got)
+	}
+	if got := y.MergeMaps("App.Labels"); got["env"] != "dev" {
+		t.Fatalf("MergeMaps = %v", got)
+	}
+	if got, err := y.GetRawSections("App.Labels"); err != nil || string(got["env"]) != `"dev"` {
+		t.Fatalf("GetRawSections = %v, %v", got, err)
+	}
+	if got, err := y.GetGlobSlice("App.Hosts"); err != nil || len(got) != 0 {
+		t.Fatalf("GetGlobSlice = %v, %v", got, err)
+	}
+	if got, _ := y.GetCIMap("App.Labels")("ENV"); got != "dev" {
+		t.Fatalf("GetCIMap = %q", got)
+	}
+	if got, err := y.GetStringMapStringResolved("App.Labels"); err != nil || got["env"] != "dev" {
+		t.Fatalf("GetStringMapStringResolved = %v, %v", got, err)
+	}
+	if got, err := y.GetStringMapStringStrict("App.Labels"); err != nil || got["env"] != "dev" {
+		t.Fatalf("GetStringMapStringStrict = %v, %v", got, err)
+	}
+	if got := y.BuildInverted("App.Roles"); !reflect.DeepEqual(got, map[string][]string{"read": {"admin"}}) {
+		t.Fatalf("BuildInverted = %v", got)
+	}
+	if containerFactory.Count(y.cachePrefix) != 0 {
+		t.Fatal("sealed reads must not populate the container cache")
+	}
+	if count := locker.count.Load(); count != 0 {
+		t.Fatalf("sealed reads accessed viper %d times", count)
+	}
+
+	// 返回值是快照的拷贝，调用方修改不影响之后的读取
+	y.GetStringMap("App")["name"] = "mutated"
+	y.GetStringSlice("App.Hosts")[0] = "mutated"
+	y.Get("App.Labels").(map[string]interface{})["env"] = "mutated"
+	if y.GetString("App.Name") != "apier" || y.GetStringSlice("App.Hosts")[0] != "a" || y.GetStringMapString("App.Labels")["env"] != "dev" {
+		t.Fatal("sealed snapshot must not be mutated through returned values")
+	}
+}
+
+// countingLocker 记录 Lock 的调用次数
+type countingLocker struct {
+	sync.Locker
+	count atomic.Int64
+}
+
+func (l *countingLocker) Lock() {
+	l.count.Add(1)
+	l.Locker.Lock()
+}
+
+func TestSealReSealsOnReload(t *testing.T) {
+	y := newTestConfig(t, "config", "App:\n  Name: before\n")
+	y.Seal()
+	writeTestConfig(t, variable.BasePath, "config", "App:\n  Name: after\n")
+	if err := y.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := y.GetString("App.Name"); got != "after" {
+		t.Fatalf("GetString after reload = %q, want after", got)
+	}
+}
+
+func BenchmarkGetStringCached(b *testing.B) {
+	benchmarkGetString(b, false)
+}
+
+func BenchmarkGetStringSealed(b *testing.B) {
+	benchmarkGetString(b, true)
+}
+
+func benchmarkGetString(b *testing.B, sealed bool) {
+	basePath := b.TempDir()
+	if err := os.MkdirAll(filepath.Join(basePath, "configs"), 0755); err != nil {
+		b.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(basePath, "configs", "bench.yml"), []byte("App:\n  Name: apier\n"), 0644); err != nil {
+		b.Fatal(err)
+	}
+	oldBasePath := variable.BasePath
+	variable.BasePath = basePath
+	defer func() { variable.BasePath = oldBasePath }()
+
+	y := CreateYamlFactory("bench").(*yamlConfig)
+	defer y.clearCache()
+	if sealed {
+		y.Seal()
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		y.GetString("App.Name")
+	}
+}
+
+func TestGetStringMapStringTrim(t *testing.T) {
+	y := newTestConfig(t, "config", "Db:\n  \"host \": \" 127.0.0.1 \"\n  \" port\": \"3306  \"\n")
+	y.SetTrimMapStrings(true)
+
+	want := map[string]string{"host": "127.0.0.1", "port": "3306"}
+	if got := y.GetStringMapString("Db"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("GetStringMapString = %q, want %q", got, want)
+	}
+	if got := y.getValueFromCache("Db").(map[string]string); !reflect.DeepEqual(got, want) {
+		t.Fatalf("cached map = %q, want normalized %q", got, want)
+	}
+}
+
+func TestRequireIf(t *testing.T) {
+	y := newTestConfig(t, "config", "Tls:\n  Enabled: false\nGrpc:\n  Tls: true\n")
+
+	if err := y.RequireIf("Tls.Cert", "Tls.Enabled", true); err != nil {
+		t.Fatalf("condition not met, key should be optional: %v", err)
+	}
+	if err := y.RequireIf("Grpc.Cert", "Grpc.Tls", true); err == nil {
+		t.Fatal("condition met, missing key should be reported")
+	}
+
+	writeTestConfig(t, variable.BasePath, "config", "Tls:\n  Enabled: true\nGrpc:\n  Tls: true\n")
+	if err := y.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	err := y.CheckRules()
+	if err == nil || !strings.Contains(err.Error(), "Tls.Cert") || !strings.Contains(err.Error(), "Grpc.Cert") {
+		t.Fatalf("CheckRules = %v, want both rules reported", err)
+	}
+}
+
+func TestGetLines(t *testing.T) {
+	y := newTestConfig(t, "config", "Allowlist: |\n  10.0.0.1\n\n    10.0.0.2  \n  10.0.0.3\n")
+
+	want := []string{"10.0.0.1", "10.0.0.2", "10.0.0.3"}
+	if got := y.GetLines("Allowlist"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("GetLines = %q, want %q", got, want)
+	}
+	if !y.keyIsCache("Allowlist#lines") {
+		t.Fatal("lines should be cached")
+	}
+}
+
+func TestRecentValues(t *testing.T) {
+	y := newTestConfig(t, "config", "Feature:\n  Rate: 10\n")
+	y.EnableHistory(3, "Feature.Rate")
+
+	for _, rate := range []string{"20", "20", "10", "30"} {
+		writeTestConfig(t, variable.BasePath, "config", "Feature:\n  Rate: "+rate+"\n")
+		if err := y.Reload(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := y.RecentValues("Feature.Rate", 5); !reflect.DeepEqual(got, []interface{}{20, 10, 30}) {
+		t.Fatalf("RecentValues = %v, want [20 10 30]", got)
+	}
+	if got := y.RecentValues("Feature.Rate", 2); !reflect.DeepEqual(got, []interface{}{10, 30}) {
+		t.Fatalf("RecentValues(2) = %v, want [10 30]", got)
+	}
+	if got := y.RecentValues("Feature.Other", 2); got != nil {
+		t.Fatalf("untracked key history = %v, want nil", got)
+	}
+	for _, n := range []int{0, -1} {
+		if got := y.RecentValues("Feature.Rate", n); got != nil {
+			t.Fatalf("RecentValues(%d) = %v, want nil", n, got)
+		}
+	}
+
+	// size 为 0 时按 1 处理，多次重新载入不能出错
+	zero := newTestConfig(t, "config", "Feature:\n  Rate: 10\n")
+	zero.EnableHistory(0, "Feature.Rate")
+	for _, rate := range []string{"20", "30"} {
+		writeTestConfig(t, variable.BasePath, "config", "Feature:\n  Rate: "+rate+"\n")
+		if err := zero.Reload(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := zero.RecentValues("Feature.Rate", 5); !reflect.DeepEqual(got, []interface{}{30}) {
+		t.Fatalf("RecentValues with size 0 = %v, want [30]", got)
+	}
+
+	// 每个键的大小独立：再次调用只影响传入的键
+	multi := newTestConfig(t, "config", "Feature:\n  Rate: 0\n  Mode: m0\n")
+	multi.EnableHistory(5, "Feature.Rate")
+	multi.EnableHistory(2, "Feature.Mode")
+	for i := 1; i <= 3; i++ {
+		writeTestConfig(t, variable.BasePath, "config", fmt.Sprintf("Feature:\n  Rate: %d\n  Mode: m%d\n", i, i))
+		if err := multi.Reload(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := multi.RecentValues("Feature.Rate", 10); !reflect.DeepEqual(got, []interface{}{0, 1, 2, 3}) {
+		t.Fatalf("Feature.Rate history = %v, want [0 1 2 3]", got)
+	}
+	if got := multi.RecentValues("Feature.Mode", 10); !reflect.DeepEqual(got, []interface{}{"m2", "m3"}) {
+		t.Fatalf("Feature.Mode history = %v, want [m2 m3]", got)
+	}
+	// 已在记录的键改为更小的大小时立即丢弃超出的旧值
+	multi.EnableHistory(2, "Feature.Rate")
+	if got := multi.RecentValues("Feature.Rate", 10); !reflect.DeepEqual(got, []interface{}{2, 3}) {
+		t.Fatalf("Feature.Rate after resize = %v, want [2 3]", got)
+	}
+}
+
+func TestGetCIMap(t *testing.T) {
+	y := newTestConfig(t, "config", "Mime:\n  Json: application/json\n")
+	lookup := y.GetCIMap("Mime")
+
+	for _, k := range []string{"json", "JSON", "Json"} {
+		if value, ok := lookup(k); !ok || value != "application/json" {
+			t.Fatalf("lookup(%q) = %q, %v", k, value, ok)
+		}
+	}
+	if _, ok := lookup("xml"); ok {
+		t.Fatal("lookup of a missing key should fail")
+	}
+
+	writeTestConfig(t, variable.BasePath, "config", "Mime:\n  Xml: application/xml\n")
+	if err := y.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if value, ok := lookup("XML"); !ok || value != "application/xml" {
+		t.Fatalf("lookup after reload = %q, %v", value, ok)
+	}
+}
+
+func TestMergeConfigAcrossFormats(t *testing.T) {
+	y := newTestConfig(t, "config", "App:\n  Name: base\n  Port: 8080\n  Debug: true\n")
+	overridePath := filepath.Join(variable.BasePath, "configs", "override.json")
+	if err := os.WriteFile(overridePath, []byte(`{"App": {"Port": 9090, "Region": "eu"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := y.MergeConfig("override.json"); err != nil {
+		t.Fatal(err)
+	}
+	assertMerged := func() {
+		t.Helper()
+		if got := y.GetInt("App.Port"); got != 9090 {
+			t.Fatalf("App.Port = %d, want json override 9090", got)
+		}
+		if got := y.GetString("App.Name"); got != "base" {
+			t.Fatalf("App.Name = %q, want yaml base", got)
+		}
+		if got := y.GetString("App.Region"); got != "eu" {
+			t.Fatalf("App.Region = %q, want eu", got)
+		}
+	}
+	assertMerged()
+
+	if err := y.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	assertMerged()
+}
+
+func TestReadBeforeLoadIsSafe(t *testing.T) {
+	var y yamlConfig
+
+	if got := y.GetString("App.Name"); got != "" {
+		t.Fatalf("GetString = %q, want empty", got)
+	}
+	if got := y.GetInt("App.Port"); got != 0 {
+		t.Fatalf("GetInt = %d, want 0", got)
+	}
+	if got := y.Get("App"); got != nil {
+		t.Fatalf("Get = %v, want nil", got)
+	}
+	if got := y.GetStringMap("App"); len(got) != 0 {
+		t.Fatalf("GetStringMap = %v, want empty", got)
+	}
+	if _, err := y.GetStringE("App.Name"); err == nil {
+		t.Fatal("GetStringE should report the unloaded state")
+	}
+	if _, err := y.GetStringMapE("App"); err == nil {
+		t.Fatal("GetStringMapE should report the unloaded state")
+	}
+	if _, err := y.GetStringMapStringValidated("App", regexp.MustCompile(".*")); err == nil {
+		t.Fatal("GetStringMapStringValidated should report the unloaded state")
+	}
+	if len(y.GetFlatStringMap("App", "")) != 0 || len(y.GetMapKeys("App")) != 0 || len(y.GetMapValues("App")) != 0 {
+		t.Fatal("map getters should return empty results before load")
+	}
+	if got := y.GetStringMapStringWithEnv("App", []string{"PATH"}, true); len(got) != 0 {
+		t.Fatalf("GetStringMapStringWithEnv = %v, want empty", got)
+	}
+	if got := y.GetStringMapStringProvided("App"); len(got) != 0 {
+		t.Fatalf("GetStringMapStringProvided = %v, want empty", got)
+	}
+	if err := y.Reload(); err == nil {
+		t.Fatal("Reload on a zero value should fail instead of panicking")
+	}
+}
+
+func TestBindAllAggregatesErrors(t *testing.T) {
+	y := newTestConfig(t, "config", "Redis:\n  Host: 127.0.0.1\n  Port: 6379\nMysql:\n  Host: \"\"\n  Port: 3306\n")
+	type dbConfig struct {
+		Host string `binding:"required"`
+		Port int    `binding:"min=1"`
+	}
+	var redis, mysql dbConfig
+
+	err := y.BindAll(map[string]interface{}{"Redis": &redis, "Mysql": &mysql})
+	if err == nil || !strings.Contains(err.Error(), "Mysql") || strings.Contains(err.Error(), "Redis") {
+		t.Fatalf("BindAll = %v, want only the Mysql spec reported", err)
+	}
+	if redis.Host != "127.0.0.1" || redis.Port != 6379 {
+		t.Fatalf("valid spec not bound: %+v", redis)
+	}
+	if mysql.Port != 3306 {
+		t.Fatalf("invalid spec should still be unmarshalled: %+v", mysql)
+	}
+}
+
+func TestGetSection(t *testing.T) {
+	y := newTestConfig(t, "config", "Redis:\n  Host: 127.0.0.1\n  Port: 6379\n  Cluster:\n    Enabled: true\n")
+	redis := y.GetSection("Redis")
+
+	if redis.GetString("Host") != "127.0.0.1" || redis.GetInt("Port") != 6379 || !redis.GetBool("Cluster.Enabled") {
+		t.Fatalf("section reads = %v, %v, %v", redis.GetString("Host"), redis.GetInt("Port"), redis.GetBool("Cluster.Enabled"))
+	}
+	if !y.keyIsCache("Redis.Host") {
+		t.Fatal("section reads should be cached under the full key")
+	}
+}
+
+func TestSecretFileProfiles(t *testing.T) {
+	y := newTestConfig(t, "config", "Db:\n  Password: from_file_config\n")
+	secretsDir := t.TempDir()
+	baseDirs := map[string]string{"dev": filepath.Join(secretsDir, "dev"), "prod": filepath.Join(secretsDir, "prod")}
+	for env, dir := range baseDirs {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "db_password"), []byte(env+"_secret\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := y.GetString("Db.Password"); got != "from_file_config" {
+		t.Fatalf("without profile = %q, want config value", got)
+	}
+
+	t.Setenv("APIER_DB_PASSWORD_FILE", "db_password")
+	y.AutomaticEnv("APIER")
+	y.SetSecretFileProfile("dev", baseDirs)
+	if got := y.GetString("Db.Password"); got != "dev_secret" {
+		t.Fatalf("dev profile = %q, want dev_secret", got)
+	}
+	y.SetSecretFileProfile("prod", baseDirs)
+	if got := y.GetString("Db.Password"); got != "prod_secret" {
+		t.Fatalf("prod profile = %q, want prod_secret", got)
+	}
+
+	t.Setenv("APIER_DB_PASSWORD_FILE", "missing")
+	if _, err := y.GetStringE("Db.Password"); err == nil {
+		t.Fatal("expected error for a missing secret file")
+	}
+}
+
+func TestUnmatchedEnvVars(t *testing.T) {
+	y := newTestConfig(t, "config", "Db:\n  Host: 127.0.0.1\n  Password: x\n")
+	t.Setenv("APIERTEST_DB_HOST", "10.0.0.1")
+	t.Setenv("APIERTEST_DB_PASSWORD_FILE", "db_password")
+	t.Setenv("APIERTEST_DB_HSOT", "typo")
+
+	if got := y.UnmatchedEnvVars("APIERTEST"); !reflect.DeepEqual(got, []string{"APIERTEST_DB_HSOT"}) {
+		t.Fatalf("UnmatchedEnvVars = %v, want [APIERTEST_DB_HSOT]", got)
+	}
+}
+
+func TestGetGlobSlice(t *testing.T) {
+	pluginDir := t.TempDir()
+	for _, name := range []string{"auth.so", "cache.so", "readme.md"} {
+		if err := os.WriteFile(filepath.Join(pluginDir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	y := newTestConfig(t, "config", "Plugins:\n  - "+filepath.Join(pluginDir, "*.so")+"\n  - "+filepath.Join(pluginDir, "*.dll")+"\n")
+
+	got, err := y.GetGlobSlice("Plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{filepath.Join(pluginDir, "auth.so"), filepath.Join(pluginDir, "cache.so")}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("GetGlobSlice = %v, want %v", got, want)
+	}
+
+	if err := os.WriteFile(filepath.Join(pluginDir, "queue.dll"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := y.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = y.GetGlobSlice("Plugins"); len(got) != 3 {
+		t.Fatalf("GetGlobSlice after reload = %v, want 3 matches", got)
+	}
+}
+
+func TestConcurrentAccessStress(t *testing.T) {
+	if testing.Short() {
+		t.Skip("stress test skipped in short mode")
+	}
+	y := newTestConfig(t, "config", "App:\n  Name: apier\n  Port: 8080\n  Debug: true\n  Ratio: 0.5\n  Timeout: 10s\n  Hosts: [a, b]\n  Notes: |\n    one\n    two\nLabels:\n  Env: dev\n  Team: core\nRoles:\n  Admin: [read, write]\nServices:\n  Web:\n    Url: http://web\n")
+
+	readers := []func(){
+		func() { y.Get("App.Name") },
+		func() { y.GetString("App.Name") },
+		func() { y.GetBool("App.Debug") },
+		func() { y.GetInt("App.Port") },
+		func() { y.GetInt32("Runtime.Int32") },
+		func() { y.GetInt64("Runtime.Int64") },
+		func() { y.GetFloat64("App.Ratio") },
+		func() { y.GetDuration("App.Timeout") },
+		func() { y.GetStringSlice("App.Hosts") },
+		func() { y.GetLines("App.Notes") },
+		func() { y.GetStringMap("Services") },
+		func() { y.GetStringMapString("Labels") },
+		func() { y.GetOrderedMapKeys("Services") },
+		func() { y.MergeMaps("Labels", "Labels") },
+		func() { y.GetCIMap("Labels")("env") },
+		func() { _, _ = y.GetStringMapStringStrict("Labels") },
+		func() { _, _ = y.GetRawSections("Services") },
+		func() { y.BuildInverted("Roles") },
+		func() { y.Warmup("App.Name") },
+		func() { _ = y.CheckRules() },
+		func() { y.AllSettingsMasked() },
+	}
+	writers := []func(i int){
+		func(i int) { _ = y.Set("Runtime.Counter", i) },
+		func(int) { _ = y.Reload() },
+		func(int) { y.clearCache() },
+	}
+
+	deadline := time.Now().Add(3 * time.Second)
+	var wg sync.WaitGroup
+	for _, read := range readers {
+		for n := 0; n < 2; n++ {
+			wg.Add(1)
+			go func(read func()) {
+				defer wg.Done()
+				for time.Now().Before(deadline) {
+					read()
+				}
+			}(read)
+		}
+	}
+	for _, write := range writers {
+		wg.Add(1)
+		go func(write func(int)) {
+			defer wg.Done()
+			for i := 0; time.Now().Before(deadline); i++ {
+				write(i)
+				time.Sleep(time.Millisecond)
+			}
+		}(write)
+	}
+	wg.Wait()
+
+	// 并发结束后缓存不能残留旧值
+	if err := y.Set("Runtime.Counter", -1); err != nil {
+		t.Fatal(err)
+	}
+	if got := y.GetInt("Runtime.Counter"); got != -1 {
+		t.Fatalf("GetInt(Runtime.Counter) = %d, want -1", got)
+	}
+	if got := y.GetString("App.Name"); got != "apier" {
+		t.Fatalf("GetString(App.Name) = %q, want apier", got)
+	}
+}
+
+func TestGetStringMapWithDefaults(t *testing.T) {
+	y := newTestConfig(t, "config", "Server:\n  Port: 9090\n  Tls:\n    Enabled: true\n")
+	defaults := map[string]interface{}{
+		"Port": 8080,
+		"Host": "0.0.0.0",
+		"Tls": map[string]interface{}{
+			"Enabled": false,
+			"Cert":    "/etc/apier/cert.pem",
+		},
+	}
+	want := map[string]interface{}{
+		"port": 9090,
+		"host": "0.0.0.0",
+		"tls": map[string]interface{}{
+			"enabled": true,
+			"cert":    "/etc/apier/cert.pem",
+		},
+	}
+	got := y.GetStringMapWithDefaults("Server", defaults)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("GetStringMapWithDefaults = %v, want %v", got, want)
+	}
+	got["tls"].(map[string]interface{})["cert"] = "changed"
+	if got := y.GetStringMapWithDefaults("Server", defaults); !reflect.DeepEqual(got, want) {
+		t.Fatalf("cached result was modified by caller: %v", got)
+	}
+	if _, ok := defaults["Tls"].(map[string]interface{})["cert"]; ok {
+		t.Fatal("defaults were modified")
+	}
+}
+
+func TestCloneE(t *testing.T) {
+	y := newTestConfig(t, "config", "App:\n  Name: parent\n")
+	writeTestConfig(t, variable.BasePath, "gorm", "Db:\n  Host: 127.0.0.1\n")
+
+	if cloned, err := y.CloneE("missing"); err == nil || cloned != nil {
+		t.Fatalf("CloneE(missing) = %v, %v, want nil instance and error", cloned, err)
+	}
+	cloned, err := y.CloneE("gorm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cloned.(*yamlConfig).clearCache)
+	if got := cloned.GetString("Db.Host"); got != "127.0.0.1" {
+		t.Fatalf("GetString(Db.Host) = %q, want 127.0.0.1", got)
+	}
+}
+
+func TestGetPath(t *testing.T) {
+	absPath := filepath.Join(os.TempDir(), "apier", "upload")
+	y := newTestConfig(t, "config", "Logs:\n  Dir: ./storage/../storage/logs\nUpload:\n  Dir: "+absPath+"\n")
+
+	if got, want := y.GetPath("Logs.Dir"), filepath.Join(variable.BasePath, "storage", "logs"); got != want {
+		t.Fatalf("GetPath(Logs.Dir) = %q, want %q", got, want)
+	}
+	if !y.keyIsCache("Logs.Dir#path") {
+		t.Fatal("resolved path should be cached")
+	}
+	if got := y.GetPath("Upload.Dir"); got != absPath {
+		t.Fatalf("GetPath(Upload.Dir) = %q, want %q", got, absPath)
+	}
+	if got := y.GetPath("Missing.Dir"); got != "" {
+		t.Fatalf("GetPath(Missing.Dir) = %q, want empty", got)
+	}
+}
+
+func TestRegisterValueMigration(t *testing.T) {
+	y := newTestConfig(t, "config", "Logs:\n  Level: warning\n  Format: json\n")
+	if got := y.GetString("Logs.Level"); got != "warning" {
+		t.Fatalf("GetString before migration = %q, want warning", got)
+	}
+
+	y.RegisterValueMigration("Logs.Level", map[string]string{"warning": "warn", "fatal": "error"})
+	for i := 0; i < 2; i++ {
+		if got := y.GetString("Logs.Level"); got != "warn" {
+			t.Fatalf("GetString(Logs.Level) = %q, want warn", got)
+		}
+	}
+	if got := y.GetString("Logs.Format"); got != "json" {
+		t.Fatalf("GetString(Logs.Format) = %q, want json", got)
+	}
+	if len(y.migrations.warned) != 1 {
+		t.Fatalf("warned = %v, want one entry", y.migrations.warned)
+	}
+
+	// 登记迁移之后先调用 Get 缓存原始值，GetString 仍返回迁移后的值
+	legacy := newTestConfig(t, "config", "Logs:\n  Level: warning\n")
+	legacy.RegisterValueMigration("Logs.Level", map[string]string{"warning": "warn"})
+	if got := legacy.Get("Logs.Level"); got != "warning" {
+		t.Fatalf("Get(Logs.Level) = %v, want raw warning", got)
+	}
+	if got := legacy.GetString("Logs.Level"); got != "warn" {
+		t.Fatalf("GetString after Get = %q, want warn", got)
+	}
+}
+
+func TestGetStringMapStringProvided(t *testing.T) {
+	y := newTestConfig(t, "config", "Upstreams:\n  Order: http://order\n  User: http://user\n")
+	calls := 0
+	y.RegisterMapProvider("Upstreams", func() map[string]string {
+		calls++
+		return map[string]string{"Local": "http://127.0.0.1:" + strconv.Itoa(20190+calls), "User": "http://computed"}
+	})
+
+	want := map[string]string{"order": "http://order", "user": "http://user", "local": "http://127.0.0.1:20191"}
+	if got := y.GetStringMapStringProvided("Upstreams"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("GetStringMapStringProvided = %v, want %v", got, want)
+	}
+	if got := y.GetStringMapStringProvided("Upstreams")["local"]; got != "http://127.0.0.1:20192" {
+		t.Fatalf("provider should be called on each read, got %q", got)
+	}
+	if got := y.GetStringMapString("Upstreams"); len(got) != 2 {
+		t.Fatalf("GetStringMapString should not include provided keys: %v", got)
+	}
+}
+
+func TestLogEffectiveConfig(t *testing.T) {
+	y := newTestConfig(t, "config", "App:\n  Name: apier\n  Port: 8080\nRedis:\n  Password: s3cret\n")
+	core, logs := observer.New(zap.InfoLevel)
+	oldLogger := variable.ZapLog
+	variable.ZapLog = zap.New(core)
+	t.Cleanup(func() { variable.ZapLog = oldLogger })
+
+	y.LogEffectiveConfig()
+	entries := logs.All()
+	if len(entries) != 1 || entries[0].Level != zap.InfoLevel {
+		t.Fatalf("entries = %v, want one info entry", entries)
+	}
+	want := "app.name = apier\napp.port = 8080\nredis.password = ******\n"
+	if got := entries[0].ContextMap()["config"]; got != want {
+		t.Fatalf("config dump = %q, want %q", got, want)
+	}
+}
+
+func TestCreateYamlFactoryWithDefaults(t *testing.T) {
+	defaults := []byte("App:\n  Name: embedded\n  Port: 8080\n  Debug: false\n")
+	basePath := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(basePath, "configs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	oldBasePath := variable.BasePath
+	variable.BasePath = basePath
+	t.Cleanup(func() { variable.BasePath = oldBasePath })
+
+	y := CreateYamlFactoryWithDefaults(defaults, "tool").(*yamlConfig)
+	t.Cleanup(y.clearCache)
+	if got := y.GetString("App.Name"); got != "embedded" {
+		t.Fatalf("GetString(App.Name) without external file = %q, want embedded", got)
+	}
+
+	writeTestConfig(t, basePath, "tool", "App:\n  Name: external\nExtra:\n  Enabled: true\n")
+	if err := y.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := y.GetString("App.Name"); got != "external" {
+		t.Fatalf("GetString(App.Name) = %q, want external", got)
+	}
+	if got := y.GetInt("App.Port"); got != 8080 {
+		t.Fatalf("GetInt(App.Port) = %d, want embedded default 8080", got)
+	}
+	if !y.GetBool("Extra.Enabled") {
+		t.Fatal("GetBool(Extra.Enabled) should come from the external file")
+	}
+}
+
+func TestSectionTypeReport(t *testing.T) {
+	y := newTestConfig(t, "config", "Redis:\n  Host: 127.0.0.1\n  Port: \"6379\"\n  Db: 0\n  Ratio: 0.5\n  Cluster:\n    Enabled: true\n    Nodes: [a, b]\n")
+	want := map[string]string{
+		"host":            "string",
+		"port":            "string",
+		"db":              "int",
+		"ratio":           "float64",
+		"cluster.enabled": "bool",
+		"cluster.nodes":   "[]interface {}",
+	}
+	if got := y.SectionTypeReport("Redis"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("SectionTypeReport = %v, want %v", got, want)
+	}
+}
+
+func TestRegisterSectionAlias(t *testing.T) {
+	y := newTestConfig(t, "config", "Redis:\n  Host: 127.0.0.1\n  Port: 6379\n  Pool:\n    Size: 10\nCacheTtl: 30s\n")
+	y.RegisterSectionAlias("Cache", "Redis")
+
+	if got := y.GetString("Cache.Host"); got != "127.0.0.1" {
+		t.Fatalf("GetString(Cache.Host) = %q, want 127.0.0.1", got)
+	}
+	if got := y.GetInt("Cache.Pool.Size"); got != 10 {
+		t.Fatalf("GetInt(Cache.Pool.Size) = %d, want 10", got)
+	}
+	if got := y.GetStringMap("Cache"); !reflect.DeepEqual(got, y.GetStringMap("Redis")) {
+		t.Fatalf("GetStringMap(Cache) = %v, want the Redis section", got)
+	}
+	if got := y.GetDuration("CacheTtl"); got != 30*time.Second {
+		t.Fatalf("GetDuration(CacheTtl) = %v, keys that only share a prefix must not be aliased", got)
+	}
+
+	y.Seal()
+	if got := y.GetInt("Cache.Port"); got != 6379 {
+		t.Fatalf("sealed GetInt(Cache.Port) = %d, want 6379", got)
+	}
+}
+
+func TestConfigChecksum(t *testing.T) {
+	content := "App:\n  Name: apier\n  Port: 8080\n  Hosts: [a, b]\nRedis:\n  Host: 127.0.0.1\n"
+	first := newTestConfig(t, "config", content).ConfigChecksum()
+	second := newTestConfig(t, "config", "Redis:\n  Host: 127.0.0.1\nApp:\n  Hosts: [a, b]\n  Port: 8080\n  Name: apier\n").ConfigChecksum()
+	if first == "" || first != second {
+		t.Fatalf("checksums of identical config differ: %q, %q", first, second)
+	}
+	if float := newTestConfig(t, "config", "App:\n  Name: apier\n  Port: 8080.0\n  Hosts: [a, b]\nRedis:\n  Host: 127.0.0.1\n").ConfigChecksum(); float != first {
+		t.Fatalf("checksum of Port 8080.0 = %q, want the same as Port 8080 %q", float, first)
+	}
+
+	y := newTestConfig(t, "config", content)
+	if err := y.Set("App.Port", 9090); err != nil {
+		t.Fatal(err)
+	}
+	if changed := y.ConfigChecksum(); changed == first {
+		t.Fatal("checksum should change after a value changes")
+	}
+}
+
+func TestGetByPattern(t *testing.T) {
+	y := newTestConfig(t, "config", "Plugins:\n  Auth:\n    Enabled: true\n    Order: 1\n  Cache:\n    Enabled: false\n    Redis:\n      Enabled: true\n  Enabled: all\n")
+
+	want := map[string]interface{}{"plugins.auth.enabled": true, "plugins.cache.enabled": false}
+	if got := y.GetByPattern("Plugins.*.Enabled"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("GetByPattern(Plugins.*.Enabled) = %v, want %v", got, want)
+	}
+	if got := y.GetByPattern("plugins.**.enabled"); len(got) != 3 {
+		t.Fatalf("GetByPattern(plugins.**.enabled) = %v, want 3 keys", got)
+	}
+	if got := y.GetByPattern("plugins.auth.ord?r"); !reflect.DeepEqual(got, map[string]interface{}{"plugins.auth.order": 1}) {
+		t.Fatalf("GetByPattern(plugins.auth.ord?r) = %v", got)
+	}
+	if _, exists := y.patterns.items["plugins.*.enabled"]; !exists {
+		t.Fatal("compiled matcher should be cached")
+	}
+	for i := 0; i < maxKeyPatterns+10; i++ {
+		y.GetByPattern("plugins.*.p" + strconv.Itoa(i))
+	}
+	if got := y.patterns.order.Len(); got != maxKeyPatterns {
+		t.Fatalf("pattern cache holds %d entries, want at most %d", got, maxKeyPatterns)
+	}
+	if _, exists := y.patterns.items["plugins.*.enabled"]; exists {
+		t.Fatal("least recently used pattern should be evicted")
+	}
+}
+
+func TestGetStringMapStringResolved(t *testing.T) {
+	y := newTestConfig(t, "config", "Api:\n  Base: http://api.local\n  V1: ${base}/v1\n  Users: ${V1}/users\n  Plain: no refs\nLoop:\n  A: ${b}/a\n  B: ${a}/b\nBroken:\n  A: ${missing}\n")
+
+	want := map[string]string{"base": "http://api.local", "v1": "http://api.local/v1", "users": "http://api.local/v1/users", "plain": "no refs"}
+	got, err := y.GetStringMapStringResolved("Api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("GetStringMapStringResolved(Api) = %v, want %v", got, want)
+	}
+	if raw := y.GetStringMapString("Api"); raw["v1"] != "${base}/v1" {
+		t.Fatalf("GetStringMapString should keep references untouched, got %q", raw["v1"])
+	}
+
+	if _, err := y.GetStringMapStringResolved("Loop"); err == nil || !strings.Contains(err.Error(), "->") {
+		t.Fatalf("cyclic reference error = %v", err)
+	}
+	if _, err := y.GetStringMapStringResolved("Broken"); err == nil {
+		t.Fatal("reference to a missing key should fail")
+	}
+}
+
+func TestPauseWatchCoalescesReloads(t *testing.T) {
+	y := newTestConfig(t, "config", "App:\n  Rev: 0\n")
+	y.EnableHistory(10, "App.Rev")
+	y.ConfigFileChangeListen()
+
+	y.PauseWatch()
+	for rev := 1; rev <= 3; rev++ {
+		writeTestConfig(t, variable.BasePath, "config", "App:\n  Rev: "+strconv.Itoa(rev)+"\n")
+		time.Sleep(2 * configChangeDebounce)
+	}
+	if got := y.GetInt("App.Rev"); got != 0 {
+		t.Fatalf("GetInt(App.Rev) while paused = %d, want 0", got)
+	}
+
+	y.ResumeWatch()
+	if got := y.GetInt("App.Rev"); got != 3 {
+		t.Fatalf("GetInt(App.Rev) after resume = %d, want 3", got)
+	}
+	// 暂停期间的多次保存只产生一次重新载入，历史中不会出现中间值
+	time.Sleep(2 * configChangeDebounce)
+	if got := y.RecentValues("App.Rev", 10); !reflect.DeepEqual(got, []interface{}{0, 3}) {
+		t.Fatalf("RecentValues = %v, want [0 3]", got)
+	}
+}
+
+func TestGetStringSet(t *testing.T) {
+	y := newTestConfig(t, "config", "Cors:\n  Origins: [https://a.dev, https://b.dev, https://a.dev]\n")
+
+	set := y.GetStringSet("Cors.Origins")
+	if len(set) != 2 {
+		t.Fatalf("GetStringSet = %v, duplicates should collapse", set)
+	}
+	if _, exists := set["https://b.dev"]; !exists {
+		t.Fatal("https://b.dev should be a member")
+	}
+	if _, exists := set["https://c.dev"]; exists {
+		t.Fatal("https://c.dev should not be a member")
+	}
+	set["https://c.dev"] = struct{}{}
+	if _, exists := y.GetStringSet("Cors.Origins")["https://c.dev"]; exists {
+		t.Fatal("modifying the returned set should not affect the cache")
+	}
+}
+
+func TestInvertMapSlice(t *testing.T) {
+	y := newTestConfig(t, "config", "Routes:\n  /users: [GET, POST]\n  /orders: [GET]\n  /health: [HEAD, GET]\n")
+
+	want := map[string][]string{
+		"GET":  {"/health", "/orders", "/users"},
+		"POST": {"/users"},
+		"HEAD": {"/health"},
+	}
+	if got := y.InvertMapSlice("Routes"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("InvertMapSlice = %v, want %v", got, want)
+	}
+
+	writeTestConfig(t, variable.BasePath, "config", "Routes:\n  /users: [PUT]\n")
+	if err := y.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := y.InvertMapSlice("Routes"); !reflect.DeepEqual(got, map[string][]string{"PUT": {"/users"}}) {
+		t.Fatalf("InvertMapSlice after reload = %v", got)
+	}
+}
+
+func TestCreateYamlFactoryStrict(t *testing.T) {
+	newTestConfig(t, "strict", "config_version: 1\nApp:\n  Name: apier\nRedis:\n  Host: 127.0.0.1\n")
+	y, err := CreateYamlFactoryStrict([]string{"app", "Redis"}, "strict")
+	if err != nil {
+		t.Fatalf("clean config should pass: %v", err)
+	}
+	t.Cleanup(y.(*yamlConfig).clearCache)
+	if got := y.GetString("App.Name"); got != "apier" {
+		t.Fatalf("GetString(App.Name) = %q, want apier", got)
+	}
+
+	writeTestConfig(t, variable.BasePath, "strict", "App:\n  Name: apier\nRedis:\n  Host: 127.0.0.1\nRedisOld:\n  Host: 10.0.0.1\nMq:\n  Url: amqp://\n")
+	if y, err := CreateYamlFactoryStrict([]string{"App", "Redis"}, "strict"); err == nil || y != nil {
+		t.Fatalf("CreateYamlFactoryStrict = %v, %v, want error", y, err)
+	} else if !strings.HasSuffix(err.Error(), "mq, redisold") {
+		t.Fatalf("error should list unknown sections, got %v", err)
+	}
+}
+
+// base64Cipher 测试用的加解密器，密文为 base64 编码后的明文
+type base64Cipher struct{}
+
+func (base64Cipher) Encrypt(plaintext string) (string, error) {
+	return base64.StdEncoding.EncodeToString([]byte(plaintext)), nil
+}
+
+func (base64Cipher) Decrypt(ciphertext string) (string, error) {
+	plaintext, err := base64.StdEncoding.DecodeString(ciphertext)
+	return string(plaintext), err
+}
+
+func TestWriteConfigAsEncryptsSecrets(t *testing.T) {
+	y := newTestConfig(t, "config", "Db:\n  Host: 127.0.0.1\n  Password: old\n")
+	if err := y.Set("Db.Password", "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	outFile := filepath.Join(variable.BasePath, "configs", "snapshot.yml")
+	if err := y.WriteConfigAs(outFile); err == nil {
+		t.Fatal("writing plaintext secrets without an encryptor should fail")
+	}
+
+	y.SetEncryptor(base64Cipher{})
+	if err := y.WriteConfigAs(outFile); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(outFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(content), "s3cret") || !strings.Contains(string(content), "enc:"+base64.StdEncoding.EncodeToString([]byte("s3cret"))) {
+		t.Fatalf("on-disk secret should be encrypted:\n%s", content)
+	}
+	if !strings.Contains(string(content), "127.0.0.1") {
+		t.Fatalf("non-sensitive values should be written as is:\n%s", content)
+	}
+	if got := y.GetString("Db.Password"); got != "s3cret" {
+		t.Fatalf("in-memory GetString = %q, want plaintext", got)
+	}
+
+	snapshot := CreateYamlFactory("snapshot").(*yamlConfig)
+	t.Cleanup(snapshot.clearCache)
+	if _, err := snapshot.GetStringE("Db.Password"); err == nil {
+		t.Fatal("reading an encrypted value without a decryptor should fail")
+	}
+	snapshot.SetDecryptor(base64Cipher{})
+	if got := snapshot.GetString("Db.Password"); got != "s3cret" {
+		t.Fatalf("decrypted GetString = %q, want s3cret", got)
+	}
+}
+
+type testRootConfig struct {
+	App struct {
+		Name    string `binding:"required"`
+		Timeout time.Duration
+	}
+	Redis struct {
+		Host  string
+		Ports []int
+		Pool  struct {
+			Size int `binding:"min=1"`
+		}
+	}
+}
+
+func TestLoadInto(t *testing.T) {
+	y := newTestConfig(t, "config", "App:\n  Name: apier\n  Timeout: 5s\nRedis:\n  Host: 127.0.0.1\n  Ports: [6379, 6380]\n  Pool:\n    Size: 10\n")
+	var root atomic.Pointer[testRootConfig]
+	if err := y.LoadInto(&root); err != nil {
+		t.Fatal(err)
+	}
+	first := root.Load()
+	if first.App.Name != "apier" || first.App.Timeout != 5*time.Second || first.Redis.Pool.Size != 10 || !reflect.DeepEqual(first.Redis.Ports, []int{6379, 6380}) {
+		t.Fatalf("LoadInto = %+v", first)
+	}
+
+	writeTestConfig(t, variable.BasePath, "config", "App:\n  Name: reloaded\n  Timeout: 1m\nRedis:\n  Host: 10.0.0.1\n  Pool:\n    Size: 20\n")
+	if err := y.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	second := root.Load()
+	if second == first || second.App.Name != "reloaded" || second.Redis.Host != "10.0.0.1" || second.Redis.Pool.Size != 20 {
+		t.Fatalf("after reload root = %+v, want a new value", second)
+	}
+	if first.App.Name != "apier" {
+		t.Fatal("previously loaded value must not be modified in place")
+	}
+
+	// 校验失败时保留旧值
+	writeTestConfig(t, variable.BasePath, "config", "App:\n  Name: \"\"\n")
+	if err := y.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if root.Load() != second {
+		t.Fatal("invalid config should keep the previous root")
+	}
+
+	var notPointer testRootConfig
+	if err := y.LoadInto(&notPointer); err == nil {
+		t.Fatal("LoadInto should reject targets that are not *atomic.Pointer[T]")
+	}
+}
+
+func TestGetNonEmptyStringSlice(t *testing.T) {
+	y := newTestConfig(t, "config", "Http:\n  Listen: [\":20191\", \":20201\"]\n  Proxies: []\n")
+
+	got, err := y.GetNonEmptyStringSlice("Http.Listen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, []string{":20191", ":20201"}) {
+		t.Fatalf("GetNonEmptyStringSlice = %v", got)
+	}
+	if !y.keyIsCache("Http.Listen") {
+		t.Fatal("non-empty result should be cached")
+	}
+	for _, keyName := range []string{"Http.Proxies", "Http.Missing"} {
+		if _, err := y.GetNonEmptyStringSlice(keyName); err == nil {
+			t.Fatalf("GetNonEmptyStringSlice(%s) should fail", keyName)
+		}
+	}
+}
+
+// slowSecretResolver 模拟较慢的远程密钥存储
+type slowSecretResolver struct {
+	delay time.Duration
+}
+
+func (s slowSecretResolver) ResolveSecret(service, account string) (string, error) {
+	time.Sleep(s.delay)
+	return service + "/" + account, nil
+}
+
+func TestSlowReads(t *testing.T) {
+	y := newTestConfig(t, "config", "Db:\n  Password: keyring:apier/db\nApp:\n  Name: apier\n")
+	y.SetSecretResolver(slowSecretResolver{delay: 50 * time.Millisecond})
+
+	if got := y.GetString("Db.Password"); got != "apier/db" {
+		t.Fatalf("GetString(Db.Password) = %q", got)
+	}
+	y.GetString("App.Name")
+	if got := y.SlowReads(20 * time.Millisecond); !reflect.DeepEqual(got, []string{"db.password"}) {
+		t.Fatalf("SlowReads = %v, want [db.password]", got)
+	}
+	if got := y.SlowReads(time.Second); len(got) != 0 {
+		t.Fatalf("SlowReads(1s) = %v, want none", got)
+	}
+}
+
+func TestExportEnvFileRoundTrip(t *testing.T) {
+	y := newTestConfig(t, "config", "App:\n  Name: api server\n  Port: 8080\n  Debug: true\n  Hosts: [a, b]\nDb:\n  Password: it's secret\n")
+	envFile := filepath.Join(variable.BasePath, "apier.env")
+	if err := y.ExportEnvFile(envFile, "apier"); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(envFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "APIER_APP_DEBUG=true\nAPIER_APP_HOSTS='[\"a\",\"b\"]'\nAPIER_APP_NAME='api server'\nAPIER_APP_PORT=8080\nAPIER_DB_PASSWORD='it'\\''s secret'\n"
+	if string(content) != want {
+		t.Fatalf("env file =\n%s\nwant\n%s", content, want)
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(want), "\n") {
+		envName, _, _ := strings.Cut(line, "=")
+		t.Cleanup(func() { os.Unsetenv(envName) })
+	}
+	if err := LoadDotEnv(envFile); err != nil {
+		t.Fatal(err)
+	}
+	imported := newTestConfig(t, "config", "App:\n  Name: other\n  Port: 1\n  Debug: false\nDb:\n  Password: other\n")
+	imported.AutomaticEnv("apier")
+	if got := imported.GetString("App.Name"); got != "api server" {
+		t.Fatalf("GetString(App.Name) = %q", got)
+	}
+	if got := imported.GetInt("App.Port"); got != 8080 {
+		t.Fatalf("GetInt(App.Port) = %d", got)
+	}
+	if !imported.GetBool("App.Debug") {
+		t.Fatal("GetBool(App.Debug) should be true")
+	}
+	if got := imported.GetString("Db.Password"); got != "it's secret" {
+		t.Fatalf("GetString(Db.Password) = %q", got)
+	}
+
+	if err := y.ExportEnvFileMasked(envFile, "apier"); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(envFile); !strings.Contains(string(content), "APIER_DB_PASSWORD='******'") {
+		t.Fatalf("masked export should hide secrets:\n%s", content)
+	}
+}
+
+func TestCheckTypeConsistency(t *testing.T) {
+	y := newTestConfig(t, "config", "App:\n  Name: apier\n  Port: 8080\n  Debug: true\n")
+	writeTestConfig(t, variable.BasePath, "config.prod", "App:\n  Name: apier-prod\n  Port: \"80\"\n  Workers: 4\n")
+
+	errs := y.CheckTypeConsistency("config", "config.prod.yml")
+	if len(errs) != 1 {
+		t.Fatalf("CheckTypeConsistency = %v, want one error", errs)
+	}
+	if msg := errs[0].Error(); !strings.Contains(msg, "app.port") || !strings.Contains(msg, "config=int") || !strings.Contains(msg, "config.prod.yml=string") {
+		t.Fatalf("unexpected report: %s", msg)
+	}
+	if errs := y.CheckTypeConsistency("config", "missing"); len(errs) != 1 {
+		t.Fatalf("missing file should be reported, got %v", errs)
+	}
+}
+
+func TestCacheSnapshotAndRestore(t *testing.T) {
+	y := newTestConfig(t, "config", "App:\n  Name: apier\n  Port: 8080\n")
+	y.Warmup("App.Name", "App.Port")
+
+	snapshot := y.CacheSnapshot()
+	if len(snapshot) != 2 || snapshot["App.Name"] != "apier" {
+		t.Fatalf("CacheSnapshot = %v", snapshot)
+	}
+
+	if err := y.Set("App.Name", "changed"); err != nil {
+		t.Fatal(err)
+	}
+	if got := y.GetString("App.Name"); got != "changed" {
+		t.Fatalf("GetString after Set = %q", got)
+	}
+
+	y.RestoreCache(snapshot)
+	if got := y.Get("App.Name"); got != "apier" {
+		t.Fatalf("Get after RestoreCache = %v, want snapshot value", got)
+	}
+	if got := y.Get("App.Port"); got != snapshot["App.Port"] {
+		t.Fatalf("Get(App.Port) = %v, want %v", got, snapshot["App.Port"])
+	}
+	if got := len(y.CacheSnapshot()); got != len(snapshot) {
+		t.Fatalf("restored cache has %d keys, want %d", got, len(snapshot))
+	}
+
+	// 导出与写入的都是拷贝，修改快照不影响缓存
+	db := newTestConfig(t, "config", "Db:\n  Host: 127.0.0.1\n")
+	db.GetStringMap("Db")
+	dbSnapshot := db.CacheSnapshot()
+	dbSnapshot["Db#extends"].(map[string]interface{})["host"] = "mutated"
+	if got := db.GetStringMap("Db")["host"]; got != "127.0.0.1" {
+		t.Fatalf("GetStringMap after mutating snapshot = %v", got)
+	}
+	db.RestoreCache(dbSnapshot)
+	dbSnapshot["Db#extends"].(map[string]interface{})["host"] = "mutated again"
+	if got := db.GetStringMap("Db")["host"]; got != "mutated" {
+		t.Fatalf("GetStringMap after RestoreCache = %v, want restored value", got)
+	}
+}
+
+func TestGetStringMapStringEnvFallback(t *testing.T) {
+	y := newTestConfig(t, "config", "Redis:\n  Host: 127.0.0.1\n")
+	t.Setenv("APIER_REDIS_HOST", "10.0.0.1")
+	t.Setenv("APIER_REDIS_PASSWORD", "from-env")
+
+	got := y.GetStringMapStringEnvFallback("Redis", []string{"Host", "Password", "Db"}, "apier_redis")
+	want := map[string]string{"host": "127.0.0.1", "password": "from-env"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("GetStringMapStringEnvFallback = %v, want %v", got, want)
+	}
+}
+
+func TestEnableDynamicLogLevel(t *testing.T) {
+	y := newTestConfig(t, "config", "Log:\n  Level: warn\n")
+	oldLevel := variable.ZapLogLevel.Level()
+	t.Cleanup(func() { variable.ZapLogLevel.SetLevel(oldLevel) })
+	core, _ := observer.New(variable.ZapLogLevel)
+	logger := zap.New(core)
+
+	if err := y.EnableDynamicLogLevel("Log.Level"); err != nil {
+		t.Fatal(err)
+	}
+	if logger.Core().Enabled(zap.InfoLevel) || !logger.Core().Enabled(zap.WarnLevel) {
+		t.Fatalf("level = %s, want warn", variable.ZapLogLevel.Level())
+	}
+
+	writeTestConfig(t, variable.BasePath, "config", "Log:\n  Level: debug\n")
+	if err := y.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if !logger.Core().Enabled(zap.DebugLevel) {
+		t.Fatalf("level after reload = %s, want debug", variable.ZapLogLevel.Level())
+	}
+
+	writeTestConfig(t, variable.BasePath, "config", "Log:\n  Level: verbose\n")
+	if err := y.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := variable.ZapLogLevel.Level(); got != zap.DebugLevel {
+		t.Fatalf("invalid level should keep previous level, got %s", got)
+	}
+	if err := y.EnableDynamicLogLevel("Log.Missing"); err == nil {
+		t.Fatal("expected error for missing key")
+	}
+}
+
+func TestGetSectionWithSchema(t *testing.T) {
+	y := newTestConfig(t, "config", "Http:\n  Port: \"8080\"\n  Extra: kept\n")
+	schema := yaml_config_interface.Schema{
+		"Port":        {Type: "int", Default: 80},
+		"Timeout":     {Type: "duration", Default: "5s"},
+		"EnableGzip":  {Type: "bool", Default: true},
+		"AllowOrigin": {Type: "[]string"},
+	}
+
+	got, err := y.GetSectionWithSchema("Http", schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"port": 8080, "timeout": 5 * time.Second, "enablegzip": true, "extra": "kept"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("GetSectionWithSchema = %#v, want %#v", got, want)
+	}
+
+	if _, err := y.GetSectionWithSchema("Http", yaml_config_interface.Schema{"Extra": {Type: "int"}}); err == nil || !strings.Contains(err.Error(), "Http.Extra") {
+		t.Fatalf("expected conversion error naming Http.Extra, got %v", err)
+	}
+}
+
+func TestMarshalStable(t *testing.T) {
+	content := "App:\n  Name: apier\n  Port: 8080\n  Ratio: 2.0\n  Tags: [b, a]\nDb:\n  Host: 127.0.0.1\n  Weight: 0.25\n"
+	first, err := newTestConfig(t, "config", content).MarshalStable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := newTestConfig(t, "config", content).MarshalStable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Fatalf("MarshalStable differs between loads:\n%s\n%s", first, second)
+	}
+	if !strings.Contains(string(first), `"ratio": 2,`) || !strings.Contains(string(first), `"weight": 0.25`) {
+		t.Fatalf("numbers not normalized:\n%s", first)
+	}
+}
+
+func TestCreateYamlFactoryFromStdin(t *testing.T) {
+	oldReader := stdinReader
+	t.Cleanup(func() { stdinReader = oldReader })
+
+	stdinReader = strings.NewReader("App:\n  Name: piped\n  Port: 9000\n")
+	y, err := CreateYamlFactoryFromStdin("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if y.GetString("App.Name") != "piped" || y.GetInt("App.Port") != 9000 {
+		t.Fatalf("unexpected values: %q %d", y.GetString("App.Name"), y.GetInt("App.Port"))
+	}
+	if err := y.Reload(); err == nil {
+		t.Fatal("expected Reload to fail for stdin config")
+	}
+
+	stdinReader = strings.NewReader(`{"app": {"name": "json"}}`)
+	if y, err = CreateYamlFactoryFromStdin("json"); err != nil || y.GetString("App.Name") != "json" {
+		t.Fatalf("json stdin: %v", err)
+	}
+}
+
+func TestEnforceTypes(t *testing.T) {
+	y := newTestConfig(t, "config", "App:\n  Port: abc\n  Debug: true\n")
+
+	err := y.EnforceTypes(map[string]string{"App.Port": "int", "App.Debug": "bool", "App.Missing": "int"})
+	if err == nil || !strings.Contains(err.Error(), "app.port") || !strings.Contains(err.Error(), "int") {
+		t.Fatalf("expected load-time error for app.port, got %v", err)
+	}
+	if strings.Contains(err.Error(), "app.debug") || strings.Contains(err.Error(), "app.missing") {
+		t.Fatalf("unexpected keys in error: %v", err)
+	}
+
+	writeTestConfig(t, variable.BasePath, "config", "App:\n  Port: 8080\n  Debug: true\n")
+	if err := y.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	y.mu.Lock()
+	err = y.checkEnforcedTypes()
+	y.mu.Unlock()
+	if err != nil {
+		t.Fatalf("checkEnforcedTypes after fix = %v", err)
+	}
+	if got := y.GetInt("App.Port"); got != 8080 {
+		t.Fatalf("App.Port = %d, want 8080", got)
+	}
+
+	// 重新载入的新配置违反强制类型时返回错误并保留旧配置
+	writeTestConfig(t, variable.BasePath, "config", "App:\n  Port: xyz\n  Debug: true\n")
+	if err := y.Reload(); err == nil || !strings.Contains(err.Error(), "app.port") {
+		t.Fatalf("Reload with type violation = %v, want error", err)
+	}
+	if got := y.GetInt("App.Port"); got != 8080 {
+		t.Fatalf("App.Port after rejected reload = %d, want old 8080", got)
+	}
+
+	// 单独重新合并一个文件时同样校验
+	writeTestConfig(t, variable.BasePath, "config", "App:\n  Port: 8080\n")
+	writeTestConfig(t, variable.BasePath, "extra", "App:\n  Debug: true\n")
+	if err := y.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if err := y.MergeConfig("extra"); err != nil {
+		t.Fatal(err)
+	}
+	writeTestConfig(t, variable.BasePath, "extra", "App:\n  Debug: maybe\n")
+	if err := y.ReloadMergedFile("extra"); err == nil || !strings.Contains(err.Error(), "app.debug") {
+		t.Fatalf("ReloadMergedFile with type violation = %v, want error", err)
+	}
+	if !y.GetBool("App.Debug") {
+		t.Fatal("App.Debug after rejected merged reload should keep old true")
+	}
+}
+
+func TestGetStringMapStringWithEnv(t *testing.T) {
+	y := newTestConfig(t, "config", "Worker:\n  Env:\n    LANG: C\n    MODE: config\n")
+	t.Setenv("MODE", "env")
+	t.Setenv("TZ", "UTC")
+	envVars := []string{"MODE", "TZ", "APIER_UNSET_VAR"}
+
+	got := y.GetStringMapStringWithEnv("Worker.Env", envVars, true)
+	want := map[string]string{"lang": "C", "mode": "env", "tz": "UTC"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("env wins = %v, want %v", got, want)
+	}
+
+	got = y.GetStringMapStringWithEnv("Worker.Env", envVars, false)
+	want = map[string]string{"lang": "C", "mode": "config", "tz": "UTC"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("config wins = %v, want %v", got, want)
+	}
+
+	got["lang"] = "mutated"
+	if y.GetStringMapStringWithEnv("Worker.Env", envVars, false)["lang"] != "C" {
+		t.Fatal("cached map was mutated through returned copy")
+	}
+}
+
+func TestSetReloadCanary(t *testing.T) {
+	y := newTestConfig(t, "config", "Db:\n  Host: good-host\n")
+	var seen string
+	var prefixes []string
+	y.SetReloadCanary(func(candidate yaml_config_interface.YamlConfigInterface) error {
+		seen = candidate.GetString("Db.Host")
+		prefixes = append(prefixes, candidate.(*yamlConfig).cachePrefix)
+		if seen == "bad-host" {
+			return errors.New("connect to bad-host refused")
+		}
+		return nil
+	})
+
+	writeTestConfig(t, variable.BasePath, "config", "Db:\n  Host: bad-host\n")
+	if err := y.Reload(); err == nil || !strings.Contains(err.Error(), "refused") {
+		t.Fatalf("expected canary rejection, got %v", err)
+	}
+	if seen != "bad-host" {
+		t.Fatalf("canary saw %q, want candidate value bad-host", seen)
+	}
+	if got := y.GetString("Db.Host"); got != "good-host" {
+		t.Fatalf("Db.Host = %q after rejected reload, want good-host", got)
+	}
+
+	writeTestConfig(t, variable.BasePath, "config", "Db:\n  Host: new-host\n")
+	if err := y.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := y.GetString("Db.Host"); got != "new-host" {
+		t.Fatalf("Db.Host = %q after accepted reload, want new-host", got)
+	}
+
+	// 被拒绝与通过的两次重新载入都不能在全局容器中留下临时实例的缓存
+	if len(prefixes) != 2 {
+		t.Fatalf("canary called %d times, want 2", len(prefixes))
+	}
+	for _, prefix := range prefixes {
+		if keys := containerFactory.Keys(prefix); len(keys) != 0 {
+			t.Fatalf("candidate cache %s leaked: %v", prefix, keys)
+		}
+	}
+}
+
+func TestSetStripSliceComments(t *testing.T) {
+	y := newTestConfig(t, "config", "Hosts:\n  - \"10.0.0.1 # primary\"\n  - \"10.0.0.2\t# backup  \"\n  - \"http://web/#/home\"\n  - \"# disabled\"\n  - \" 10.0.0.3 \"\n")
+	if got := y.GetStringSlice("Hosts"); len(got) != 5 {
+		t.Fatalf("without option GetStringSlice = %q, want raw entries", got)
+	}
+
+	y.SetStripSliceComments(true)
+	want := []string{"10.0.0.1", "10.0.0.2", "http://web/#/home", "10.0.0.3"}
+	if got := y.GetStringSlice("Hosts"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("GetStringSlice = %q, want %q", got, want)
+	}
+}
+
+func TestConfigModTime(t *testing.T) {
+	y := newTestConfig(t, "config", "App:\n  Name: apier\n")
+	configFile := filepath.Join(variable.BasePath, "configs", "config.yml")
+	fixed := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes(configFile, fixed, fixed); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := y.ConfigModTime(); err != nil || !got.Equal(fixed) {
+		t.Fatalf("ConfigModTime = %v, %v, want %v", got, err, fixed)
+	}
+
+	writeTestConfig(t, variable.BasePath, "config", "App:\n  Name: changed\n")
+	info, err := os.Stat(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := y.ConfigModTime(); err != nil || !got.Equal(info.ModTime()) || got.Equal(fixed) {
+		t.Fatalf("ConfigModTime after write = %v, %v, want %v", got, err, info.ModTime())
+	}
+
+	oldReader := stdinReader
+	t.Cleanup(func() { stdinReader = oldReader })
+	stdinReader = strings.NewReader("App:\n  Name: piped\n")
+	before := time.Now()
+	piped, err := CreateYamlFactoryFromStdin("yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := piped.ConfigModTime(); err != nil || got.Before(before) {
+		t.Fatalf("stdin ConfigModTime = %v, %v, want load time after %v", got, err, before)
+	}
+}
+
+type testPriority int
+
+func parseTestPriority(value interface{}) (testPriority, error) {
+	switch cast.ToString(value) {
+	case "low":
+		return 1, nil
+	case "high":
+		return 2, nil
+	}
+	return 0, fmt.Errorf("unknown priority %v", value)
+}
+
+func TestGetSliceOf(t *testing.T) {
+	y := newTestConfig(t, "config", "Queue:\n  Priorities: [low, high, urgent, low]\n  Backoff: [1s, 500ms]\n  Name: jobs\n")
+
+	priorities, err := GetSliceOf(y, "Queue.Priorities", parseTestPriority)
+	if want := []testPriority{1, 2, 1}; !reflect.DeepEqual(priorities, want) {
+		t.Fatalf("GetSliceOf = %v, want %v", priorities, want)
+	}
+	if err == nil || !strings.Contains(err.Error(), "Queue.Priorities") || !strings.Contains(err.Error(), "2") || !strings.Contains(err.Error(), "urgent") {
+		t.Fatalf("expected error for element 2, got %v", err)
+	}
+
+	backoff, err := GetSliceOf(y, "Queue.Backoff", cast.ToDurationE)
+	if err != nil || !reflect.DeepEqual(backoff, []time.Duration{time.Second, 500 * time.Millisecond}) {
+		t.Fatalf("GetSliceOf durations = %v, %v", backoff, err)
+	}
+	if missing, err := GetSliceOf(y, "Queue.Missing", cast.ToIntE); err != nil || len(missing) != 0 {
+		t.Fatalf("missing key = %v, %v", missing, err)
+	}
+	if _, err := GetSliceOf(y, "Queue.Name", cast.ToStringE); err == nil {
+		t.Fatal("expected error for non-list value")
+	}
+
+	// GetStringSlice 先在同一键名下缓存了 []string
+	ports := newTestConfig(t, "config", "Http:\n  Ports: [8080, 8081]\n")
+	ports.GetStringSlice("Http.Ports")
+	if got, err := GetSliceOf(ports, "Http.Ports", cast.ToIntE); err != nil || !reflect.DeepEqual(got, []int{8080, 8081}) {
+		t.Fatalf("GetSliceOf after GetStringSlice = %v, %v", got, err)
+	}
+}
+
+func TestReloadMergedFile(t *testing.T) {
+	y := newTestConfig(t, "config", "App:\n  Name: apier\nDb:\n  Port: 3306\n")
+	writeTestConfig(t, variable.BasePath, "db", "Db:\n  Host: 10.0.0.1\n")
+	if err := y.MergeConfig("db.yml"); err != nil {
+		t.Fatal(err)
+	}
+	y.Warmup("App.Name", "Db.Port", "Db.Host")
+
+	writeTestConfig(t, variable.BasePath, "db", "Db:\n  Host: 10.0.0.2\n")
+	if err := y.ReloadMergedFile("db.yml"); err != nil {
+		t.Fatal(err)
+	}
+	if got := y.GetString("Db.Host"); got != "10.0.0.2" {
+		t.Fatalf("Db.Host = %q, want 10.0.0.2", got)
+	}
+	if !y.keyIsCache("App.Name") || !y.keyIsCache("Db.Port") {
+		t.Fatal("cache of keys not provided by db.yml should survive a targeted reload")
+	}
+
+	// db.yml 开始覆盖主配置中的键，无法单独合并，退回完整的 Reload
+	writeTestConfig(t, variable.BasePath, "db", "Db:\n  Host: 10.0.0.3\nApp:\n  Name: from-db\n")
+	if err := y.ReloadMergedFile("db.yml"); err != nil {
+		t.Fatal(err)
+	}
+	if y.keyIsCache("Db.Port") {
+		t.Fatal("ambiguous change should fall back to a full reload")
+	}
+	if got := y.GetString("App.Name"); got != "from-db" {
+		t.Fatalf("App.Name = %q, want from-db", got)
+	}
+	if err := y.ReloadMergedFile("other.yml"); err == nil {
+		t.Fatal("expected error for a file that was never merged")
+	}
+}
+
+func TestGetStringMapStringValidated(t *testing.T) {
+	y := newTestConfig(t, "config", "Upstreams:\n  user: http://user:8080\n  order: https://order\nBroken:\n  user: http://user:8080\n  order: order:9000\n  pay: ftp://pay\n")
+	urlPattern := regexp.MustCompile(`^https?://`)
+
+	got, err := y.GetStringMapStringValidated("Upstreams", urlPattern)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"user": "http://user:8080", "order": "https://order"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("GetStringMapStringValidated = %v, want %v", got, want)
+	}
+	if !y.keyIsCache("Upstreams#validated#" + urlPattern.String()) {
+		t.Fatal("validated map should be cached")
+	}
+
+	if _, err := y.GetStringMapStringValidated("Broken", urlPattern); err == nil || !strings.Contains(err.Error(), "order=order:9000, pay=ftp://pay") {
+		t.Fatalf("expected error listing invalid entries, got %v", err)
+	}
+	if y.keyIsCache("Broken#validated#" + urlPattern.String()) {
+		t.Fatal("invalid map must not be cached")
+	}
+}
+
+func TestViewOverridesWithoutMutatingBase(t *testing.T) {
+	y := newTestConfig(t, "config", "Tenant:\n  Name: default\n  Db:\n    Host: 127.0.0.1\n    Port: 3306\n")
+	view := y.View(map[string]interface{}{"Tenant.Db.Host": "10.0.0.9"})
+
+	if got := view.GetString("Tenant.Db.Host"); got != "10.0.0.9" {
+		t.Fatalf("view Tenant.Db.Host = %q, want override", got)
+	}
+	if got := view.GetInt("Tenant.Db.Port"); got != 3306 {
+		t.Fatalf("view Tenant.Db.Port = %d, want base value", got)
+	}
+	if got := view.GetString("Tenant.Name"); got != "default" {
+		t.Fatalf("view Tenant.Name = %q, want base value", got)
+	}
+	if got := view.GetStringMapString("Tenant.Db"); got["host"] != "10.0.0.9" || got["port"] != "3306" {
+		t.Fatalf("view GetStringMapString = %v", got)
+	}
+	if got := view.GetSection("Tenant.Db").GetString("Host"); got != "10.0.0.9" {
+		t.Fatalf("view section Host = %q", got)
+	}
+
+	if err := view.Set("Tenant.Name", "tenant-a"); err != nil {
+		t.Fatal(err)
+	}
+	if got := view.GetString("Tenant.Name"); got != "tenant-a" {
+		t.Fatalf("view Tenant.Name after Set = %q", got)
+	}
+	if y.GetString("Tenant.Db.Host") != "127.0.0.1" || y.GetString("Tenant.Name") != "default" {
+		t.Fatal("base instance must not be affected by the view")
+	}
+}
+
+func TestNilCachePolicy(t *testing.T) {
+	y := newTestConfig(t, "config", "App:\n  Name: apier\n")
+	y.AutomaticEnv("APIERNIL")
+
+	// 默认不缓存不存在的键，之后设置的环境变量可以立即读到
+	if got := y.GetString("App.Token"); got != "" {
+		t.Fatalf("GetString before env = %q", got)
+	}
+	if y.keyIsCache("App.Token") {
+		t.Fatal("missing key must not be cached by default")
+	}
+	t.Setenv("APIERNIL_APP_TOKEN", "first")
+	if got := y.GetString("App.Token"); got != "first" {
+		t.Fatalf("GetString after env = %q, want first", got)
+	}
+
+	// NilCacheStore 复现掩盖问题：缓存的零值在重新载入前一直生效
+	y.SetNilCachePolicy(yaml_config_interface.NilCacheStore)
+	if got := y.Get("App.Secret"); got != nil {
+		t.Fatalf("Get before env = %v", got)
+	}
+	t.Setenv("APIERNIL_APP_SECRET", "hidden")
+	if got := y.Get("App.Secret"); got != nil {
+		t.Fatalf("NilCacheStore should keep the cached nil, got %v", got)
+	}
+	if err := y.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if got := y.Get("App.Secret"); got != "hidden" {
+		t.Fatalf("Get after reload = %v, want hidden", got)
+	}
+}
+
+func TestGetMapKeysAndValues(t *testing.T) {
+	y := newTestConfig(t, "config", "Features:\n  sso: on\n  audit: 30\n  billing:\n    plan: pro\n")
+
+	keys := y.GetMapKeys("Features")
+	if want := []string{"audit", "billing", "sso"}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("GetMapKeys = %v, want %v", keys, want)
+	}
+	values := y.GetMapValues("Features")
+	if want := []interface{}{30, map[string]interface{}{"plan": "pro"}, "on"}; !reflect.DeepEqual(values, want) {
+		t.Fatalf("GetMapValues = %#v, want %#v", values, want)
+	}
+	if !y.keyIsCache("Features#keys") || !y.keyIsCache("Features#values") {
+		t.Fatal("keys and values should be cached")
+	}
+
+	keys[0] = "mutated"
+	values[1].(map[string]interface{})["plan"] = "free"
+	if y.GetMapKeys("Features")[0] != "audit" || y.GetMapValues("Features")[1].(map[string]interface{})["plan"] != "pro" {
+		t.Fatal("cached results were mutated through returned slices")
+	}
+}
+
+func TestOnChangeReceivesReloadEvent(t *testing.T) {
+	before, after := "App:\n  Name: before\n  Port: 8080\n", "App:\n  Name: after\n  Debug: true\n"
+	y := newTestConfig(t, "config", before)
+	events := make(chan yaml_config_interface.ReloadEvent, 4)
+	cancel := y.OnChange(func(event yaml_config_interface.ReloadEvent) {
+		// 回调中可以读取配置
+		if y.GetString("App.Name") == "after" {
+			events <- event
+		}
+	})
+	defer cancel()
+
+	y.ConfigFileChangeListen()
+	start := time.Now()
+	writeTestConfig(t, variable.BasePath, "config", after)
+
+	var event yaml_config_interface.ReloadEvent
+	select {
+	case event = <-events:
+	case <-time.After(2 * time.Second):
+		t.Fatal("OnChange callback not called after file change")
+	}
+	// 摘要是配置文件字节的 sha256
+	if event.OldChecksum != testContentChecksum(before) || event.NewChecksum != testContentChecksum(after) {
+		t.Fatalf("unexpected checksums: %+v", event)
+	}
+	if event.Time.Before(start) {
+		t.Fatalf("event time %v before change %v", event.Time, start)
+	}
+	want := []yaml_config_interface.ChangedKey{
+		{Key: "app.debug", Type: yaml_config_interface.KeyAdded, NewValue: true},
+		{Key: "app.name", Type: yaml_config_interface.KeyChanged, OldValue: "before", NewValue: "after"},
+		{Key: "app.port", Type: yaml_config_interface.KeyRemoved, OldValue: 8080},
+	}
+	if !reflect.DeepEqual(event.Changes, want) {
+		t.Fatalf("Changes = %+v, want %+v", event.Changes, want)
+	}
+}
+
+func TestOnChangeChecksumCoversFileContent(t *testing.T) {
+	content := "App:\n  Name: apier\n"
+	y := newTestConfig(t, "config", content)
+	var events []yaml_config_interface.ReloadEvent
+	cancel := y.OnChange(func(event yaml_config_interface.ReloadEvent) { events = append(events, event) })
+	defer cancel()
+
+	// 只改注释：配置没有变化，摘要仍然不同
+	commented := "# 应用配置\nApp:\n  Name: apier\n"
+	writeTestConfig(t, variable.BasePath, "config", commented)
+	if err := y.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	// 环境变量覆盖不计入摘要
+	t.Setenv("APIER_APP_NAME", "from-env")
+	y.AutomaticEnv("APIER")
+	if err := y.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+	if first := events[0]; first.OldChecksum != testContentChecksum(content) || first.NewChecksum != testContentChecksum(commented) || len(first.Changes) != 0 {
+		t.Fatalf("comment-only edit event = %+v", first)
+	}
+	if second := events[1]; second.OldChecksum != second.NewChecksum {
+		t.Fatalf("env override changed file checksum: %+v", second)
+	}
+}
+
+// testContentChecksum 单个配置文件内容的期望摘要
+func testContentChecksum(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestConfigDeclaredTransforms(t *testing.T) {
+	y := newTestConfig(t, "config", "Log:\n  Level: WARNING\nApp:\n  Region: \"  cn-north \"\n  Name: Apier\ntransforms:\n  log.level: lowercase\n  app.region: [trim, uppercase]\n  app.name: reverse\n")
+	y.RegisterValueMigration("Log.Level", map[string]string{"warning": "warn"})
+
+	if got := y.GetString("Log.Level"); got != "warn" {
+		t.Fatalf("Log.Level = %q, want lowercased and migrated warn", got)
+	}
+	if got := y.GetString("App.Region"); got != "CN-NORTH" {
+		t.Fatalf("App.Region = %q, want CN-NORTH", got)
+	}
+	if got := y.GetString("App.Name"); got != "Apier" {
+		t.Fatalf("unknown transform should be skipped, got %q", got)
+	}
+
+	// Get 与 GetString 使用同一键名，先读取原始值不能影响转换，之后 Get 仍返回原始值
+	upper := newTestConfig(t, "config", "Log:\n  Level: WARN\ntransforms:\n  log.level: lowercase\n")
+	if got := upper.Get("Log.Level"); got != "WARN" {
+		t.Fatalf("Get(Log.Level) = %v, want raw WARN", got)
+	}
+	if got := upper.GetString("Log.Level"); got != "warn" {
+		t.Fatalf("GetString after Get = %q, want warn", got)
+	}
+	if got := upper.Get("Log.Level"); got != "WARN" {
+		t.Fatalf("Get after GetString = %v, want raw WARN", got)
+	}
+}
+
+func TestDiffConfigs(t *testing.T) {
+	a := newTestConfig(t, "config", "App:\n  Name: apier\n  Port: 8080\n  Ratio: 0.5\n  Debug: true\n")
+	b := newTestConfig(t, "config", "App:\n  Name: apier-v2\n  Port: 8080.0\n  Ratio: 0.75\n  Workers: 4\n")
+
+	want := []yaml_config_interface.ChangedKey{
+		{Key: "app.debug", Type: yaml_config_interface.KeyRemoved, OldValue: true},
+		{Key: "app.name", Type: yaml_config_interface.KeyChanged, OldValue: "apier", NewValue: "apier-v2"},
+		{Key: "app.ratio", Type: yaml_config_interface.KeyChanged, OldValue: 0.5, NewValue: 0.75},
+		{Key: "app.workers", Type: yaml_config_interface.KeyAdded, NewValue: 4},
+	}
+	if got := DiffConfigs(a, b); !reflect.DeepEqual(got, want) {
+		t.Fatalf("DiffConfigs = %+v, want %+v", got, want)
+	}
+	if got := DiffConfigs(a, a); len(got) != 0 {
+		t.Fatalf("DiffConfigs(a, a) = %+v, want empty", got)
+	}
+}
+
+func TestGetStrictDuration(t *testing.T) {
+	y := newTestConfig(t, "config", "Http:\n  ReadTimeout: 30s\n  WriteTimeout: \"30\"\n  IdleTimeout: 30\n  Backoff: 1.5\n  Grace: soon\n")
+
+	if got, err := y.GetStrictDuration("Http.ReadTimeout"); err != nil || got != 30*time.Second {
+		t.Fatalf("GetStrictDuration(30s) = %v, %v", got, err)
+	}
+	for _, keyName := range []string{"Http.WriteTimeout", "Http.IdleTimeout", "Http.Backoff"} {
+		if _, err := y.GetStrictDuration(keyName); err == nil || !strings.Contains(err.Error(), keyName) {
+			t.Fatalf("GetStrictDuration(%s) should reject bare numbers, got %v", keyName, err)
+		}
+	}
+	if _, err := y.GetStrictDuration("Http.Grace"); err == nil || !strings.Contains(err.Error(), "duration") {
+		t.Fatalf("expected parse error for invalid string, got %v", err)
+	}
+	if _, err := y.GetStrictDuration("Http.Missing"); err == nil {
+		t.Fatal("expected error for missing key")
+	}
+
+	// GetDuration 先在同一键名下缓存了 time.Duration，GetStrictDuration 仍按原始值校验
+	other := newTestConfig(t, "config", "Http:\n  ReadTimeout: 30s\n  IdleTimeout: 30\n")
+	other.GetDuration("Http.ReadTimeout")
+	other.GetDuration("Http.IdleTimeout")
+	if got, err := other.GetStrictDuration("Http.ReadTimeout"); err != nil || got != 30*time.Second {
+		t.Fatalf("GetStrictDuration after GetDuration = %v, %v", got, err)
+	}
+	if _, err := other.GetStrictDuration("Http.IdleTimeout"); err == nil {
+		t.Fatal("GetStrictDuration after GetDuration should still reject bare numbers")
+	}
+}
+
+func TestPersistAndLoadCache(t *testing.T) {
+	y := newTestConfig(t, "config", "App:\n  Name: apier\n  Port: 8080\n  Timeout: 5s\n  Hosts: [a, b]\nDb:\n  Password: secret\n")
+	y.GetString("App.Name")
+	y.GetInt("App.Port")
+	y.GetDuration("App.Timeout")
+	y.GetStringSlice("App.Hosts")
+	y.GetString("Db.Password")
+	path := filepath.Join(t.TempDir(), "cache.json")
+
+	if err := y.PersistCache(path); err != nil {
+		t.Fatal(err)
+	}
+	if content, _ := os.ReadFile(path); strings.Contains(string(content), "secret") {
+		t.Fatalf("sensitive value persisted: %s", content)
+	}
+
+	y.clearCache()
+	if err := y.LoadPersistedCache(path); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"App.Name": "apier", "App.Port": 8080, "App.Timeout": 5 * time.Second, "App.Hosts": []string{"a", "b"}}
+	if got := y.CacheSnapshot(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("restored cache = %#v, want %#v", got, want)
+	}
+	if y.GetInt("App.Port") != 8080 || y.GetDuration("App.Timeout") != 5*time.Second {
+		t.Fatal("typed reads should hit the restored cache")
+	}
+
+	writeTestConfig(t, variable.BasePath, "config", "App:\n  Name: changed\n")
+	if err := y.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if err := y.LoadPersistedCache(path); err == nil {
+		t.Fatal("expected stale cache error after the config changed")
+	}
+	if got := y.GetString("App.Name"); got != "changed" {
+		t.Fatalf("App.Name = %q after rejected load", got)
+	}
+}
+
+func TestGetFlatStringMap(t *testing.T) {
+	y := newTestConfig(t, "config", "Db:\n  Name: apier\n  Master:\n    Host: 127.0.0.1\n    Port: 3306\n  Slaves:\n    Hosts: [10.0.0.2, 10.0.0.3]\n")
+
+	want := map[string]string{
+		"name":          "apier",
+		"master__host":  "127.0.0.1",
+		"master__port":  "3306",
+		"slaves__hosts": `["10.0.0.2","10.0.0.3"]`,
+	}
+	if got := y.GetFlatStringMap("Db", "__"); !reflect.DeepEqual(got, want) {
+		t.Fatalf("GetFlatStringMap = %v, want %v", got, want)
+	}
+	if !y.keyIsCache("Db#flat#__") {
+		t.Fatal("flattened map should be cached")
+	}
+	if got := y.GetFlatStringMap("Db", ""); got["master.host"] != "127.0.0.1" {
+		t.Fatalf("default separator should be '.', got %v", got)
+	}
+}
+
+func TestLoadIntoWithSupersede(t *testing.T) {
+	y := newTestConfig(t, "config", "App:\n  Name: apier\n  Timeout: 5s\nRedis:\n  Pool:\n    Size: 10\n")
+	var root atomic.Pointer[testRootConfig]
+	var superseded []*testRootConfig
+	if err := y.LoadIntoWithSupersede(&root, func(old interface{}) {
+		// 回调时新值已经存入，且可以读取配置
+		if root.Load() == old || y.GetString("App.Name") == "" {
+			t.Error("onSupersede should run after the swap without holding the lock")
+		}
+		superseded = append(superseded, old.(*testRootConfig))
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(superseded) != 0 {
+		t.Fatal("first bind has nothing to supersede")
+	}
+	first := root.Load()
+
+	writeTestConfig(t, variable.BasePath, "config", "App:\n  Name: reloaded\n  Timeout: 1m\nRedis:\n  Pool:\n    Size: 10\n")
+	if err := y.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if len(superseded) != 1 || superseded[0] != first || superseded[0].App.Name != "apier" {
+		t.Fatalf("onSupersede should receive the previous root, got %+v", superseded)
+	}
+
+	// 校验失败时保持旧值，不调用回调
+	writeTestConfig(t, variable.BasePath, "config", "App:\n  Name: \"\"\n")
+	if err := y.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if len(superseded) != 1 {
+		t.Fatalf("failed rebind should not supersede, got %d calls", len(superseded))
+	}
+}
+
+func TestAuditEnvCoverage(t *testing.T) {
+	y := newTestConfig(t, "config", "Db:\n  Host: 127.0.0.1\n  max-conns: 10\n  \"read timeout\": 5s\nRedis:\n  Pool_Size: 10\n")
+	y.AutomaticEnv("APIER")
+
+	want := []string{"db.max-conns", "db.read timeout"}
+	if got := y.AuditEnvCoverage(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("AuditEnvCoverage = %v, want %v", got, want)
+	}
+
+	// 默认值、命令行参数、Set 设置的键不来自配置文件，不在检查范围内
+	y.mu.Lock()
+	y.viper.SetDefault("Cache.default-ttl", "1m")
+	y.mu.Unlock()
+	flagSet := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flagSet.String("log-level", "info", "")
+	if err := y.BindPFlags(flagSet); err != nil {
+		t.Fatal(err)
+	}
+	if err := y.Set("Runtime.node-id", "n1"); err != nil {
+		t.Fatal(err)
+	}
+	if got := y.AuditEnvCoverage(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("AuditEnvCoverage with non-file keys = %v, want %v", got, want)
+	}
+}
+
+func TestViewDerivedGetters(t *testing.T) {
+	y := newTestConfig(t, "config", "Tenant:\n  Email: base@example.com\n  Timeout: 5s\n  Hosts: [a, b]\n  Data: data\n  Db:\n    Host: 127.0.0.1\n    Port: \"3306\"\n")
+	view := y.View(map[string]interface{}{
+		"Tenant.Email":   "tenant@example.com",
+		"Tenant.Timeout": "30",
+		"Tenant.Hosts":   []string{"c", "c"},
+		"Tenant.Data":    "tenant-data",
+		"Tenant.Db.Host": "10.0.0.9",
+	})
+
+	if got, err := view.GetStringChecked("Tenant.Email", func(string) error { return nil }); err != nil || got != "tenant@example.com" {
+		t.Fatalf("GetStringChecked = %q, %v", got, err)
+	}
+	if _, err := view.GetStrictDuration("Tenant.Timeout"); err == nil {
+		t.Fatal("GetStrictDuration should check the unitless override")
+	}
+	if got := view.GetStringSet("Tenant.Hosts"); !reflect.DeepEqual(got, map[string]struct{}{"c": {}}) {
+		t.Fatalf("GetStringSet = %v", got)
+	}
+	if got, _ := view.GetNonEmptyStringSlice("Tenant.Hosts"); !reflect.DeepEqual(got, []string{"c", "c"}) {
+		t.Fatalf("GetNonEmptyStringSlice = %v", got)
+	}
+	if got := view.GetPath("Tenant.Data"); got != filepath.Join(variable.BasePath, "tenant-data") {
+		t.Fatalf("GetPath = %q", got)
+	}
+	if got := view.GetFlatStringMap("Tenant.Db", "__"); got["host"] != "10.0.0.9" || got["port"] != "3306" {
+		t.Fatalf("GetFlatStringMap = %v", got)
+	}
+	if got, err := view.GetStringMapStringStrict("Tenant.Db"); err != nil || got["host"] != "10.0.0.9" {
+		t.Fatalf("GetStringMapStringStrict = %v, %v", got, err)
+	}
+	if got := view.GetMapValues("Tenant.Db"); !reflect.DeepEqual(got, []interface{}{"10.0.0.9", "3306"}) {
+		t.Fatalf("GetMapValues = %v", got)
+	}
+	if got, _ := view.GetCIMap("Tenant.Db")("HOST"); got != "10.0.0.9" {
+		t.Fatalf("GetCIMap(HOST) = %q", got)
+	}
+
+	// 未覆盖的键仍然读取基础实例
+	if got := view.GetMapKeys("Tenant"); !reflect.DeepEqual(got, y.GetMapKeys("Tenant")) {
+		t.Fatalf("GetMapKeys = %v", got)
+	}
+	if got, err := y.GetStrictDuration("Tenant.Timeout"); err != nil || got != 5*time.Second {
+		t.Fatalf("base GetStrictDuration = %v, %v", got, err)
+	}
+}
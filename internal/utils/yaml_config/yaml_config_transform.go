@@ -0,0 +1,39 @@
+package yaml_config
+
+import (
+	"apier/internal/global/custom_errors"
+	"github.com/spf13/cast"
+	"strings"
+)
+
+// 配置文件中声明读取转换的配置段，例如：
+//
+//	transforms:
+//	  log.level: lowercase
+//	  app.region: [trim, uppercase]
+const transformsKey = "transforms"
+
+// 内置的读取转换
+var builtinTransforms = map[string]func(string) string{
+	"lowercase": strings.ToLower,
+	"uppercase": strings.ToUpper,
+	"trim":      strings.TrimSpace,
+}
+
+// transformValue 按 transforms 配置段为该键声明的转换依次处理取值，可以是单个转换名，也可以是按顺序执行的列表
+// GetString 在缓存之前应用转换，因此缓存中保存的是转换后的值；未知的转换名记录告警后跳过
+func (y *yamlConfig) transformValue(keyName, value string) string {
+	declared := y.Get(transformsKey + "." + strings.ToLower(keyName))
+	if declared == nil {
+		return value
+	}
+	for _, name := range cast.ToStringSlice(declared) {
+		transform, exists := builtinTransforms[strings.ToLower(name)]
+		if !exists {
+			logWarn(custom_errors.ErrorsConfigTransformUnknown + name + "，相关键：" + keyName)
+			continue
+		}
+		value = transform(value)
+	}
+	return value
+}
@@ -0,0 +1,118 @@
+package yaml_config
+
+import (
+	"apier/internal/global/custom_errors"
+	"errors"
+	"fmt"
+	"github.com/spf13/viper"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 声明类型与对应 getter 缓存值的 Go 类型
+var declaredGoTypes = map[string]reflect.Type{
+	"string":   reflect.TypeOf(""),
+	"int":      reflect.TypeOf(0),
+	"int32":    reflect.TypeOf(int32(0)),
+	"int64":    reflect.TypeOf(int64(0)),
+	"float64":  reflect.TypeOf(float64(0)),
+	"bool":     reflect.TypeOf(false),
+	"duration": reflect.TypeOf(time.Duration(0)),
+	"[]string": reflect.TypeOf([]string(nil)),
+	"map":      reflect.TypeOf(map[string]interface{}(nil)),
+}
+
+// declaredTypes 键 => 声明类型，键名统一为小写；enforced 为 EnforceTypes 登记、载入时强制校验的部分
+type declaredTypes struct {
+	mu       sync.Mutex
+	types    map[string]string
+	enforced map[string]string
+}
+
+func newDeclaredTypes() *declaredTypes {
+	return &declaredTypes{types: make(map[string]string), enforced: make(map[string]string)}
+}
+
+// DeclareTypes 声明键的类型，可选值与 LoadOptions.Types 相同，供 VerifyCacheTypes 检查使用
+func (y *yamlConfig) DeclareTypes(spec map[string]string) {
+	y.types.mu.Lock()
+	defer y.types.mu.Unlock()
+	for keyName, typeName := range spec {
+		y.types.types[strings.ToLower(keyName)] = typeName
+	}
+}
+
+// VerifyCacheTypes 扫描已缓存的配置项，找出缓存值类型与声明类型不一致的键
+// 同一个键先后被不同类型的 getter 读取时，后读取的 getter 会在类型断言处 panic，该方法用于调试阶段提前发现这类问题
+func (y *yamlConfig) VerifyCacheTypes() []error {
+	y.types.mu.Lock()
+	defer y.types.mu.Unlock()
+
+	cacheKeys := containerFactory.Keys(y.cachePrefix)
+	sort.Strings(cacheKeys)
+	errs := make([]error, 0)
+	for _, cacheKey := range cacheKeys {
+		keyName := strings.TrimPrefix(cacheKey, y.cachePrefix)
+		typeName, declared := y.types.types[strings.ToLower(keyName)]
+		if !declared {
+			continue
+		}
+		value := containerFactory.Get(cacheKey)
+		if goType, ok := declaredGoTypes[typeName]; !ok || reflect.TypeOf(value) != goType {
+			errs = append(errs, fmt.Errorf(custom_errors.ErrorsConfigCacheTypeMismatch, keyName, value, typeName))
+		}
+	}
+	return errs
+}
+
+// EnforceTypes 声明键的类型并立即按当前配置校验（可选值与 LoadOptions.Types 相同），例如 {"app.port": "int"}
+// 值无法转换为声明类型的键汇总后返回错误，应在启动阶段调用，把不同 getter 读取同一个键时的类型问题提前暴露
+// 登记的类型同时用于 VerifyCacheTypes；之后每次重新载入先校验新配置，未通过时 Reload 返回错误并保留旧配置；配置中不存在的键不做检查
+func (y *yamlConfig) EnforceTypes(spec map[string]string) error {
+	y.DeclareTypes(spec)
+	y.types.mu.Lock()
+	for keyName, typeName := range spec {
+		y.types.enforced[strings.ToLower(keyName)] = typeName
+	}
+	y.types.mu.Unlock()
+
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	return y.checkEnforcedTypes()
+}
+
+// checkEnforcedTypes 按键名顺序校验全部强制类型，调用方需持有 y.mu
+func (y *yamlConfig) checkEnforcedTypes() error {
+	return y.checkEnforcedTypesIn(y.viper)
+}
+
+// hasEnforcedTypes 判断是否登记过强制类型
+func (y *yamlConfig) hasEnforcedTypes() bool {
+	y.types.mu.Lock()
+	defer y.types.mu.Unlock()
+	return len(y.types.enforced) > 0
+}
+
+// checkEnforcedTypesIn 用强制类型校验指定的 viper，重新载入时用于校验尚未生效的新配置，调用方需持有 y.mu
+func (y *yamlConfig) checkEnforcedTypesIn(v *viper.Viper) error {
+	y.types.mu.Lock()
+	defer y.types.mu.Unlock()
+	keys := make([]string, 0, len(y.types.enforced))
+	for keyName := range y.types.enforced {
+		keys = append(keys, keyName)
+	}
+	sort.Strings(keys)
+	var errs []error
+	for _, keyName := range keys {
+		if !v.IsSet(keyName) {
+			continue
+		}
+		if err := checkValueType(keyName, v.Get(keyName), y.types.enforced[keyName]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
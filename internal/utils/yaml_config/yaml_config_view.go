@@ -0,0 +1,382 @@
+package yaml_config
+
+import (
+	"apier/internal/global/custom_errors"
+	"apier/internal/utils/yaml_config/yaml_config_interface"
+	"errors"
+	"github.com/spf13/cast"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// configView 在基础实例之上叠加一层覆盖值的只读视图，覆盖值的键名统一为小写
+// 未重写的方法直接委托给基础实例，因此覆盖只作用于下面重写的 getter
+type configView struct {
+	yaml_config_interface.YamlConfigInterface
+	mu        sync.RWMutex
+	overrides map[string]interface{}
+}
+
+// View 返回叠加了 overrides 的轻量视图，适合请求级、租户级的配置：读取时先查覆盖值，再读取基础实例，基础实例本身不受影响
+// 覆盖对 Get、GetString、GetInt 等标量 getter 以及 GetStringMap、GetStringMapString、GetSection 生效，Tenant.Db.Host 之类的覆盖也会体现在 Tenant.Db 字典中
+// 在此基础上派生的 getter 同样经过视图读取：GetStringChecked、GetStrictDuration、GetNonEmptyStringSlice、GetStringSet、GetLines、GetGlobSlice、GetPath、
+// GetStringMapWithDefaults、GetSectionWithSchema、GetStringMapStringStrict、GetStringMapStringValidated、GetFlatStringMap、GetOrderedMapKeys、GetMapKeys、GetMapValues、MergeMaps、GetCIMap
+// 以下读取方法不支持覆盖，只返回基础实例的值：GetStringMapStringResolved、GetStringMapStringProvided、GetStringMapStringEnvFallback、GetStringMapStringWithEnv、
+// GetRawSections、BuildInverted、InvertMapSlice、GetByPattern、AllSettingsFlattened、AllSettingsMasked、SectionTypeReport、DebugString、LoadInto、BindAll
+// 在视图上调用 Set 只修改视图的覆盖值；其它方法（Reload、订阅、校验等）直接作用于基础实例
+func (y *yamlConfig) View(overrides map[string]interface{}) yaml_config_interface.YamlConfigInterface {
+	return newConfigView(y, overrides)
+}
+
+func newConfigView(base yaml_config_interface.YamlConfigInterface, overrides map[string]interface{}) *configView {
+	view := &configView{YamlConfigInterface: base, overrides: make(map[string]interface{}, len(overrides))}
+	for keyName, value := range overrides {
+		view.overrides[strings.ToLower(keyName)] = deepCopyValue(value)
+	}
+	return view
+}
+
+// override 查找键的覆盖值
+func (v *configView) override(keyName string) (interface{}, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	value, exists := v.overrides[strings.ToLower(keyName)]
+	return deepCopyValue(value), exists
+}
+
+// overridden 判断键本身或其下级键是否有覆盖值，没有时派生的 getter 直接使用基础实例的结果（以及缓存）
+func (v *configView) overridden(keyName string) bool {
+	lowerKey := strings.ToLower(keyName)
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	for overrideKey := range v.overrides {
+		if overrideKey == lowerKey || strings.HasPrefix(overrideKey, lowerKey+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// childOverrides 将 keyName 下级键的覆盖值展开为嵌套字典，例如 db.host 相对于 db 展开为 {host: ...}
+func (v *configView) childOverrides(keyName string) map[string]interface{} {
+	prefix := strings.ToLower(keyName) + "."
+	children := make(map[string]interface{})
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	for overrideKey, value := range v.overrides {
+		if !strings.HasPrefix(overrideKey, prefix) {
+			continue
+		}
+		path := strings.Split(strings.TrimPrefix(overrideKey, prefix), ".")
+		node := children
+		for _, segment := range path[:len(path)-1] {
+			child, ok := node[segment].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				node[segment] = child
+			}
+			node = child
+		}
+		node[path[len(path)-1]] = deepCopyValue(value)
+	}
+	return children
+}
+
+// View 在当前视图之上再叠加一层覆盖值
+func (v *configView) View(overrides map[string]interface{}) yaml_config_interface.YamlConfigInterface {
+	return newConfigView(v, overrides)
+}
+
+// Set 只修改视图的覆盖值，不影响基础实例
+func (v *configView) Set(keyName string, value interface{}) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.overrides[strings.ToLower(keyName)] = deepCopyValue(value)
+	return nil
+}
+
+// GetSection 返回经过视图读取的配置段读取器
+func (v *configView) GetSection(keyName string) yaml_config_interface.Section {
+	return &section{y: v, prefix: keyName + "."}
+}
+
+// Get 先查覆盖值，再读取基础实例
+func (v *configView) Get(keyName string) interface{} {
+	if value, exists := v.override(keyName); exists {
+		return value
+	}
+	return v.YamlConfigInterface.Get(keyName)
+}
+
+// GetString 字符串格式返回值
+func (v *configView) GetString(keyName string) string {
+	if value, exists := v.override(keyName); exists {
+		return cast.ToString(value)
+	}
+	return v.YamlConfigInterface.GetString(keyName)
+}
+
+// GetStringE 与 GetString 相同，但会返回基础实例的解析错误
+func (v *configView) GetStringE(keyName string) (string, error) {
+	if value, exists := v.override(keyName); exists {
+		return cast.ToStringE(value)
+	}
+	return v.YamlConfigInterface.GetStringE(keyName)
+}
+
+// GetBool 布尔格式返回值
+func (v *configView) GetBool(keyName string) bool {
+	if value, exists := v.override(keyName); exists {
+		return cast.ToBool(value)
+	}
+	return v.YamlConfigInterface.GetBool(keyName)
+}
+
+// GetInt 整数格式返回值
+func (v *configView) GetInt(keyName string) int {
+	if value, exists := v.override(keyName); exists {
+		return cast.ToInt(value)
+	}
+	return v.YamlConfigInterface.GetInt(keyName)
+}
+
+// GetInt32 int32 格式返回值
+func (v *configView) GetInt32(keyName string) int32 {
+	if value, exists := v.override(keyName); exists {
+		return cast.ToInt32(value)
+	}
+	return v.YamlConfigInterface.GetInt32(keyName)
+}
+
+// GetInt64 int64 格式返回值
+func (v *configView) GetInt64(keyName string) int64 {
+	if value, exists := v.override(keyName); exists {
+		return cast.ToInt64(value)
+	}
+	return v.YamlConfigInterface.GetInt64(keyName)
+}
+
+// GetFloat64 float64 格式返回值
+func (v *configView) GetFloat64(keyName string) float64 {
+	if value, exists := v.override(keyName); exists {
+		return cast.ToFloat64(value)
+	}
+	return v.YamlConfigInterface.GetFloat64(keyName)
+}
+
+// GetDuration 时间单位格式返回值
+func (v *configView) GetDuration(keyName string) time.Duration {
+	if value, exists := v.override(keyName); exists {
+		return cast.ToDuration(value)
+	}
+	return v.YamlConfigInterface.GetDuration(keyName)
+}
+
+// GetStringSlice 字符串切片格式返回值
+func (v *configView) GetStringSlice(keyName string) []string {
+	if value, exists := v.override(keyName); exists {
+		return cast.ToStringSlice(value)
+	}
+	return v.YamlConfigInterface.GetStringSlice(keyName)
+}
+
+// GetStringMap 字典格式返回值，下级键的覆盖值合并到基础实例的字典中
+func (v *configView) GetStringMap(keyName string) map[string]interface{} {
+	value, _ := v.GetStringMapE(keyName)
+	return value
+}
+
+// GetStringMapE 与 GetStringMap 相同，但会返回基础实例的解析错误
+func (v *configView) GetStringMapE(keyName string) (map[string]interface{}, error) {
+	if value, exists := v.override(keyName); exists {
+		return cast.ToStringMapE(value)
+	}
+	value, err := v.YamlConfigInterface.GetStringMapE(keyName)
+	if value == nil {
+		value = map[string]interface{}{}
+	}
+	return deepMerge(value, v.childOverrides(keyName)), err
+}
+
+// GetStringMapString 字符串字典格式返回值，直接下级键的覆盖值合并到基础实例的字典中
+func (v *configView) GetStringMapString(keyName string) map[string]string {
+	if value, exists := v.override(keyName); exists {
+		return cast.ToStringMapString(value)
+	}
+	value := v.YamlConfigInterface.GetStringMapString(keyName)
+	for subKey, subValue := range v.childOverrides(keyName) {
+		value[subKey] = cast.ToString(subValue)
+	}
+	return value
+}
+
+// GetStringChecked 经过视图读取字符串后执行校验函数
+func (v *configView) GetStringChecked(keyName string, check func(string) error) (string, error) {
+	value, err := v.GetStringE(keyName)
+	if err != nil {
+		return "", err
+	}
+	if err := check(value); err != nil {
+		return "", err
+	}
+	return value, nil
+}
+
+// GetStrictDuration 与基础实例相同，要求覆盖值同样带单位
+func (v *configView) GetStrictDuration(keyName string) (time.Duration, error) {
+	if value, exists := v.override(keyName); exists {
+		return strictDuration(keyName, value)
+	}
+	return v.YamlConfigInterface.GetStrictDuration(keyName)
+}
+
+// GetNonEmptyStringSlice 经过视图读取字符串切片，结果为空时返回错误
+func (v *configView) GetNonEmptyStringSlice(keyName string) ([]string, error) {
+	if !v.overridden(keyName) {
+		return v.YamlConfigInterface.GetNonEmptyStringSlice(keyName)
+	}
+	value := v.GetStringSlice(keyName)
+	if len(value) == 0 {
+		return nil, errors.New(custom_errors.ErrorsConfigEmptySlice + keyName)
+	}
+	return value, nil
+}
+
+// GetStringSet 经过视图读取字符串切片并转为集合
+func (v *configView) GetStringSet(keyName string) map[string]struct{} {
+	if !v.overridden(keyName) {
+		return v.YamlConfigInterface.GetStringSet(keyName)
+	}
+	return stringSet(v.GetStringSlice(keyName))
+}
+
+// GetLines 经过视图读取多行字符串并按行拆分
+func (v *configView) GetLines(keyName string) []string {
+	if !v.overridden(keyName) {
+		return v.YamlConfigInterface.GetLines(keyName)
+	}
+	return splitLines(v.GetString(keyName))
+}
+
+// GetGlobSlice 经过视图读取 glob 模式并展开
+func (v *configView) GetGlobSlice(keyName string) ([]string, error) {
+	if !v.overridden(keyName) {
+		return v.YamlConfigInterface.GetGlobSlice(keyName)
+	}
+	return expandGlobs(keyName, v.GetStringSlice(keyName))
+}
+
+// GetPath 经过视图读取路径，规则与基础实例相同
+func (v *configView) GetPath(keyName string) string {
+	if !v.overridden(keyName) {
+		return v.YamlConfigInterface.GetPath(keyName)
+	}
+	return resolvePath(v.GetString(keyName))
+}
+
+// GetStringMapWithDefaults 将经过视图读取的配置段合并到默认值之上
+func (v *configView) GetStringMapWithDefaults(keyName string, defaults map[string]interface{}) map[string]interface{} {
+	if !v.overridden(keyName) {
+		return v.YamlConfigInterface.GetStringMapWithDefaults(keyName, defaults)
+	}
+	return deepMerge(lowerKeys(defaults), v.GetStringMap(keyName))
+}
+
+// GetSectionWithSchema 按 schema 处理经过视图读取的配置段
+func (v *configView) GetSectionWithSchema(keyName string, schema yaml_config_interface.Schema) (map[string]interface{}, error) {
+	if !v.overridden(keyName) {
+		return v.YamlConfigInterface.GetSectionWithSchema(keyName, schema)
+	}
+	value := v.GetStringMap(keyName)
+	return value, applySchema(keyName, value, schema)
+}
+
+// GetStringMapStringStrict 经过视图读取字典，任一子项不是字符串时返回错误
+func (v *configView) GetStringMapStringStrict(keyName string) (map[string]string, error) {
+	if !v.overridden(keyName) {
+		return v.YamlConfigInterface.GetStringMapStringStrict(keyName)
+	}
+	value, err := v.GetStringMapE(keyName)
+	if err != nil {
+		return nil, errors.New(custom_errors.ErrorsConfigNotStringMap + keyName)
+	}
+	return strictStringMap(keyName, value)
+}
+
+// GetStringMapStringValidated 经过视图读取字符串字典并要求每个值都匹配 pattern
+func (v *configView) GetStringMapStringValidated(keyName string, pattern *regexp.Regexp) (map[string]string, error) {
+	if !v.overridden(keyName) {
+		return v.YamlConfigInterface.GetStringMapStringValidated(keyName, pattern)
+	}
+	value := v.GetStringMapString(keyName)
+	if err := validateStringMap(keyName, value, pattern); err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// GetFlatStringMap 将经过视图读取的配置段展开为一层的字符串字典
+func (v *configView) GetFlatStringMap(keyName, sep string) map[string]string {
+	if !v.overridden(keyName) {
+		return v.YamlConfigInterface.GetFlatStringMap(keyName, sep)
+	}
+	if sep == "" {
+		sep = "."
+	}
+	value := make(map[string]string)
+	flattenStringMap(value, "", sep, v.GetStringMap(keyName))
+	return value
+}
+
+// GetOrderedMapKeys 按字典序返回经过视图读取的字典的键
+func (v *configView) GetOrderedMapKeys(keyName string) []string {
+	if !v.overridden(keyName) {
+		return v.YamlConfigInterface.GetOrderedMapKeys(keyName)
+	}
+	return sortedMapKeys(v.GetStringMap(keyName))
+}
+
+// GetMapKeys 与 GetOrderedMapKeys 相同
+func (v *configView) GetMapKeys(keyName string) []string {
+	if !v.overridden(keyName) {
+		return v.YamlConfigInterface.GetMapKeys(keyName)
+	}
+	return sortedMapKeys(v.GetStringMap(keyName))
+}
+
+// GetMapValues 按键的字典序返回经过视图读取的字典的值
+func (v *configView) GetMapValues(keyName string) []interface{} {
+	if !v.overridden(keyName) {
+		return v.YamlConfigInterface.GetMapValues(keyName)
+	}
+	return mapValues(v.GetStringMap(keyName))
+}
+
+// MergeMaps 依次经过视图读取多个字符串字典并合并
+func (v *configView) MergeMaps(keys ...string) map[string]string {
+	value := make(map[string]string)
+	for _, keyName := range keys {
+		for subKey, subValue := range v.GetStringMapString(keyName) {
+			value[subKey] = subValue
+		}
+	}
+	return value
+}
+
+// GetCIMap 返回不区分大小写的查找函数，每次调用都经过视图读取，因此 Set 修改的覆盖值同样生效
+func (v *configView) GetCIMap(keyName string) func(k string) (string, bool) {
+	base := v.YamlConfigInterface.GetCIMap(keyName)
+	return func(k string) (string, bool) {
+		if !v.overridden(keyName) {
+			return base(k)
+		}
+		for subKey, subValue := range v.GetStringMapString(keyName) {
+			if strings.EqualFold(subKey, k) {
+				return subValue, true
+			}
+		}
+		return "", false
+	}
+}
@@ -0,0 +1,65 @@
+package yaml_config
+
+import (
+	"apier/internal/global/custom_errors"
+	"go.uber.org/zap"
+	"time"
+)
+
+// watchState 文件监听的状态，全部字段由 yamlConfig.mu 保护
+// timer 为文件变化后等待重新载入的定时器；paused 期间的文件变化只记录到 pending，恢复监听时合并为一次重新载入
+type watchState struct {
+	timer   *time.Timer
+	paused  bool
+	pending bool
+}
+
+// scheduleReload 文件变化后延迟重新载入，延迟期间再次变化则重新计时
+func (y *yamlConfig) scheduleReload() {
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	if y.watch.paused {
+		y.watch.pending = true
+		return
+	}
+	if y.watch.timer != nil {
+		y.watch.timer.Reset(configChangeDebounce)
+		return
+	}
+	y.watch.timer = time.AfterFunc(configChangeDebounce, func() {
+		y.mu.Lock()
+		if y.watch.paused {
+			// 暂停之前已经开始计时的变化，同样留到恢复监听时处理
+			y.watch.pending = true
+			y.mu.Unlock()
+			return
+		}
+		y.mu.Unlock()
+		if err := y.Reload(); err != nil {
+			logError(custom_errors.ErrorsConfigReloadFail, zap.Error(err))
+		}
+	})
+}
+
+// PauseWatch 暂停文件监听触发的重新载入，适合需要多次保存才能完成的批量修改，避免载入只改了一半的配置
+// 暂停期间 Reload、SIGHUP 信号触发的重新载入不受影响
+func (y *yamlConfig) PauseWatch() {
+	y.mu.Lock()
+	defer y.mu.Unlock()
+	y.watch.paused = true
+}
+
+// ResumeWatch 恢复文件监听，暂停期间发生过文件变化时，无论变化了多少次都只立即重新载入一次
+func (y *yamlConfig) ResumeWatch() {
+	y.mu.Lock()
+	pending := y.watch.pending
+	y.watch.paused = false
+	y.watch.pending = false
+	y.mu.Unlock()
+	if !pending {
+		return
+	}
+	if err := y.Reload(); err != nil {
+		logError(custom_errors.ErrorsConfigReloadFail, zap.Error(err))
+	}
+}